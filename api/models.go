@@ -0,0 +1,243 @@
+// Package api holds the types the RankPoll API sends and receives,
+// shared by the server and the Go client.
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// Option is one of the choices a poll offers.
+// Ballots reference it by Id so its Label can change without breaking them.
+type Option struct {
+	Id          uuid.UUID
+	Label       string
+	Description string
+	Link        string
+	// Username of the voter that wrote this option in.
+	// Empty for options the poll was created with.
+	AddedBy string
+}
+
+func (o Option) IsWriteIn() bool {
+	return o.AddedBy != ""
+}
+
+type Rank struct {
+	OptionId uuid.UUID
+	Position uint
+}
+
+type Vote struct {
+	Username string
+	Ranking  []Rank
+}
+
+// ResultStatus tells how a poll ended, clients should branch on it
+// instead of checking the winner.
+type ResultStatus string
+
+const (
+	// The poll has a winner.
+	StatusDecided ResultStatus = "DECIDED"
+	// The last round ended with more than one option with the most votes.
+	StatusTied ResultStatus = "TIED"
+	// No ballots were cast.
+	StatusNoVotes ResultStatus = "NO_VOTES"
+	// Not enough ballots were cast to meet the poll's quorum.
+	StatusNoQuorum ResultStatus = "NO_QUORUM"
+)
+
+type PollSummary struct {
+	Status   ResultStatus
+	Winner   string
+	WinnerId uuid.UUID
+	// Only set when the poll ended on a tie.
+	TiedOptions     []uuid.UUID
+	WinnerVoteCount uint
+	TotalVoteCount  uint
+	// Amount of ballots cast in the poll.
+	BallotCount     uint
+	RequiredBallots uint
+	// When the quorum is not met no winner is named.
+	QuorumMet bool
+	Rounds    []Round
+}
+
+type WriteInSettings struct {
+	Allowed bool
+	// Max amount of write-ins (pending or approved) the poll accepts.
+	Limit uint
+}
+
+// Eligibility restricts who can vote in a poll.
+// When it has neither allowed voters nor invitation codes anyone can vote.
+type Eligibility struct {
+	// Usernames allowed to vote.
+	AllowedVoters []string
+	// Maps each one-time invitation code to whether it was already redeemed.
+	InvitationCodes map[string]bool
+}
+
+func (e Eligibility) IsRestricted() bool {
+	return len(e.AllowedVoters) > 0 || len(e.InvitationCodes) > 0
+}
+
+// CanVote checks if a voter is eligible, either by its username
+// or by an invitation code. It returns whether the code must be redeemed.
+// The caller must check the username belongs to the voter.
+func (e Eligibility) CanVote(username string, invitationCode string) (bool, error) {
+	if !e.IsRestricted() {
+		return false, nil
+	}
+
+	if username != "" && slices.Contains(e.AllowedVoters, username) {
+		return false, nil
+	}
+
+	if invitationCode == "" {
+		return false, fmt.Errorf("user %s is not allowed to vote", username)
+	}
+
+	redeemed, found := e.InvitationCodes[invitationCode]
+	if !found {
+		return false, errors.New("the invitation code is not valid")
+	}
+
+	if redeemed {
+		return false, errors.New("the invitation code was already used")
+	}
+
+	return true, nil
+}
+
+func (e Eligibility) EligibleCount() uint {
+	return uint(len(e.AllowedVoters) + len(e.InvitationCodes))
+}
+
+// Quorum is the minimum participation a poll needs for its result to count.
+type Quorum struct {
+	MinBallots uint
+	// Percentage of the eligible voters that must vote, from 0 to 100.
+	// Only valid for polls with restricted eligibility.
+	MinPercentage uint
+}
+
+func (q Quorum) RequiredBallots(eligibleCount uint) uint {
+	byPercentage := (eligibleCount*q.MinPercentage + 99) / 100
+	return max(q.MinBallots, byPercentage)
+}
+
+// RoundReason explains what happened at the end of a round.
+type RoundReason string
+
+const (
+	// An option reached the threshold and won.
+	ReasonElected RoundReason = "ELECTED"
+	// No more rounds can be made, the option with most votes won.
+	ReasonLastRound RoundReason = "LAST_ROUND"
+	// No more rounds can be made and the options with most votes are tied.
+	ReasonTied RoundReason = "TIED"
+	// No option reached the threshold, the next preferences are counted.
+	ReasonNextPreferences RoundReason = "NEXT_PREFERENCES"
+)
+
+// Round is the transcript of a single counting round.
+// Bucklin never eliminates options, each round adds the next preferences
+// of every ballot to the tally of the previous one instead.
+type Round struct {
+	Number uint
+	// Maps the id of every option to the votes it got.
+	Tally map[string]uint
+	// Votes an option needs to win the round.
+	Threshold uint
+	// Sorted ids of the options with most votes on the round,
+	// more than one means they're tied.
+	Leaders []uuid.UUID
+	Reason  RoundReason
+	// Maps option ids to the votes they got from the next preferences counted on this round.
+	Transfers map[string]uint
+	// Ballots that don't count for any option on this round.
+	ExhaustedBallots uint
+}
+
+// Normally T will be time.Time but sometimes it needs to be something else.
+// For example an int64 for representing Unix time.
+//
+// Ballots cast before a write-in option got approved don't rank it,
+// tallies treat those options as unranked on that ballot.
+type Room[T any] struct {
+	Id       uuid.UUID
+	Title    string
+	Owner    string
+	Options  []Option
+	WriteIns WriteInSettings
+	// Write-ins waiting for the owner's approval.
+	PendingOptions []Option
+	Eligibility    Eligibility
+	Quorum         Quorum
+	// Hides who cast each ballot outside of the voting checks.
+	SecretBallot bool
+	Votes        map[string]Vote
+	Summary      *PollSummary
+	ValidUntil   T
+}
+
+func (r Room[T]) FindOption(id uuid.UUID) (Option, bool) {
+	for _, opt := range r.Options {
+		if opt.Id == id {
+			return opt, true
+		}
+	}
+	return Option{}, false
+}
+
+func (r Room[T]) FindOptionByLabel(label string) (Option, bool) {
+	for _, opt := range r.Options {
+		if opt.Label == label {
+			return opt, true
+		}
+	}
+	return Option{}, false
+}
+
+// Ballots returns the votes of the room ordered by voter.
+// If the ballot is secret voters are removed and the order is shuffled.
+func (r Room[T]) Ballots() []Vote {
+	voteKeys := make([]string, 0, len(r.Votes))
+	for key := range r.Votes {
+		voteKeys = append(voteKeys, key)
+	}
+	slices.Sort(voteKeys)
+
+	ballots := make([]Vote, 0, len(voteKeys))
+	for _, key := range voteKeys {
+		vote := r.Votes[key]
+		if r.SecretBallot {
+			vote.Username = ""
+		}
+		ballots = append(ballots, vote)
+	}
+
+	if r.SecretBallot {
+		rand.Shuffle(len(ballots), func(i, j int) {
+			ballots[i], ballots[j] = ballots[j], ballots[i]
+		})
+	}
+
+	return ballots
+}
+
+func (r Room[T]) WriteInCount() uint {
+	count := uint(len(r.PendingOptions))
+	for _, opt := range r.Options {
+		if opt.IsWriteIn() {
+			count++
+		}
+	}
+	return count
+}
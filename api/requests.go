@@ -0,0 +1,92 @@
+package api
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CreateOrLoginUserRequest struct {
+	Username string
+	Password string
+}
+
+type CreateOrLoginUserResponse struct {
+	Msg string
+}
+
+type PollOptionRequest struct {
+	Label       string
+	Description string
+	Link        string
+}
+
+type CreatePollRequest struct {
+	Title string
+	// Username of the user creating the poll.
+	Username string
+	// Deprecated: Only labels can be supplied here, use Options instead.
+	PollOptions     []string
+	Options         []PollOptionRequest
+	PollingDuration time.Duration
+	AllowWriteIns   bool
+	// Max amount of write-ins, the configured default is used if it's 0.
+	WriteInLimit uint
+	// Usernames allowed to vote, if empty and there are no invitations anyone can vote.
+	AllowedVoters []string
+	// Amount of one-time invitation codes to generate.
+	InvitationCount uint
+	// Minimum amount of ballots for the poll to have a winner.
+	QuorumBallots uint
+	// Minimum percentage of eligible voters that must vote for the poll to have a winner.
+	QuorumPercentage uint
+	// Hides who cast each ballot on poll info and exports.
+	SecretBallot bool
+}
+
+type CreatePollResponse struct {
+	PollId uuid.UUID
+	Msg    string
+	// Only returned when creating the poll, share them with the voters.
+	InvitationCodes []string
+}
+
+// VoteInPollRequest needs the HTTP Basic credentials of the user when it's one of the allowed voters of the poll.
+type VoteInPollRequest struct {
+	Username string
+	PollId   uuid.UUID
+	// Maps each option id to the rank the user gave it.
+	Ranking map[uuid.UUID]uint
+	// Deprecated: Maps option labels to ranks, use Ranking instead.
+	// Only used when Ranking is empty.
+	Options map[string]uint
+	// Needed when the user isn't on the allowed voters of the poll.
+	// Lets anonymous users vote without a username.
+	InvitationCode string
+}
+
+// AddWriteInRequest only needs the HTTP Basic credentials of the owner, whose write-ins skip the moderation.
+type AddWriteInRequest struct {
+	Username    string
+	Label       string
+	Description string
+	Link        string
+}
+
+type AddWriteInResponse struct {
+	OptionId uuid.UUID
+	Msg      string
+}
+
+// ModerateWriteInRequest needs the HTTP Basic credentials of the owner of the poll.
+type ModerateWriteInRequest struct {
+	Approve bool
+}
+
+// UpdatePollRequest needs the HTTP Basic credentials of the owner of the poll.
+type UpdatePollRequest struct {
+	// Fields left null aren't changed.
+	Title *string
+	// Unix time in milliseconds, a time in the past closes the poll right away.
+	ValidUntil *int64
+}
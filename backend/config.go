@@ -0,0 +1,461 @@
+package main
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ElrohirGT/RankPoll/response"
+)
+
+// Duration is a time.Duration written like "5s" in config files.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+type ServerConfig struct {
+	Addr              string
+	ReadTimeout       Duration
+	ReadHeaderTimeout Duration
+	WriteTimeout      Duration
+	IdleTimeout       Duration
+	// Time the server keeps serving after /readyz starts failing on shutdown,
+	// so load balancers stop routing to it before it closes its connections.
+	DrainDelay Duration
+	// Time given to in-flight requests to finish when shutting down.
+	ShutdownGrace Duration
+	// Serve HTTPS when both are set, the files are read again on SIGHUP.
+	TLSCertFile string
+	TLSKeyFile  string
+}
+
+type LogConfig struct {
+	Level slog.Level
+	// Either "text" or "json".
+	Format string
+	// Bodies longer than this are truncated, 0 doesn't log bodies.
+	BodyLimit int
+	// Fields redacted from logged bodies besides DefaultRedactedFields.
+	RedactFields []string
+}
+
+type CORSConfig struct {
+	// Origins allowed to call the API, "*" allows any and "https://*.example.com" any subdomain.
+	Origins []string
+	Methods []string
+	Headers []string
+	// Lets browsers send cookies, can't be used with "*" origins.
+	AllowCredentials bool
+	// How long browsers can cache preflight responses, 0 doesn't cache them.
+	MaxAge Duration
+}
+
+type FrontendConfig struct {
+	// Serve the embedded frontend on every path that isn't an API route.
+	Enabled bool
+	// Where the frontend calls the API, empty uses the origin it's served from.
+	ApiBaseUrl string
+}
+
+type LimitsConfig struct {
+	MaxBodyBytes int64
+	// Invitation codes a poll can generate.
+	MaxInvitationCount uint
+	// Write-ins allowed in a poll when its owner doesn't set a limit.
+	DefaultWriteInLimit uint
+	// Ballots a poll import can have, counting the weight of BLT ballots.
+	MaxImportedBallots uint
+}
+
+// RateLimit lets clients make PerMinute requests on average and up to Burst at once.
+type RateLimit struct {
+	PerMinute float64
+	Burst     uint
+}
+
+type RateLimitConfig struct {
+	Enabled bool
+	// Proxies whose X-Forwarded-For is trusted, as IPs or CIDRs.
+	TrustedProxies []string
+	// Limits by route path of MountHandlers, routes not listed aren't limited.
+	Routes map[string]RateLimit
+	// Failed logins before the account is locked.
+	MaxLoginFailures uint
+	LockoutDuration  Duration
+}
+
+type Config struct {
+	Server    ServerConfig
+	Log       LogConfig
+	CORS      CORSConfig
+	RateLimit RateLimitConfig
+	Frontend  FrontendConfig
+	// Directory where polls are persisted, empty keeps them only in memory.
+	StoragePath string
+	Limits      LimitsConfig
+}
+
+// AppConfig is the configuration the server is running with.
+var AppConfig = DefaultConfig()
+
+func DefaultConfig() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:              "127.0.0.1:8080",
+			ReadTimeout:       Duration(10 * time.Second),
+			ReadHeaderTimeout: Duration(5 * time.Second),
+			WriteTimeout:      Duration(30 * time.Second),
+			IdleTimeout:       Duration(2 * time.Minute),
+			DrainDelay:        Duration(5 * time.Second),
+			ShutdownGrace:     Duration(3 * time.Second),
+		},
+		Log: LogConfig{
+			Level:        slog.LevelInfo,
+			Format:       "text",
+			BodyLimit:    2048,
+			RedactFields: []string{},
+		},
+		CORS: CORSConfig{
+			Origins: []string{"*"},
+			Methods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions},
+			Headers: []string{"Content-Type", "Origin", "Accept", "Authorization", "token", response.RequestIdHeader},
+			MaxAge:  Duration(10 * time.Minute),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:        true,
+			TrustedProxies: []string{},
+			Routes: map[string]RateLimit{
+				"/api/v1/users":                             {PerMinute: 10, Burst: 5},
+				"/api/v1/polls":                             {PerMinute: 30, Burst: 10},
+				"/api/v1/polls/import":                      {PerMinute: 5, Burst: 2},
+				"/api/v1/polls/{pollId}":                    {PerMinute: 60, Burst: 20},
+				"/api/v1/polls/{pollId}/votes":              {PerMinute: 60, Burst: 10},
+				"/api/v1/polls/{pollId}/options":            {PerMinute: 30, Burst: 10},
+				"/api/v1/polls/{pollId}/options/{optionId}": {PerMinute: 30, Burst: 10},
+				"/api/v1/polls/{pollId}/export/blt":         {PerMinute: 10, Burst: 5},
+				"/api/v1/polls/{pollId}/export/csv":         {PerMinute: 10, Burst: 5},
+				"/api/v1/polls/{pollId}/export/jsonl":       {PerMinute: 10, Burst: 5},
+			},
+			MaxLoginFailures: 5,
+			LockoutDuration:  Duration(15 * time.Minute),
+		},
+		Frontend: FrontendConfig{
+			Enabled: true,
+		},
+		Limits: LimitsConfig{
+			MaxBodyBytes:        1 << 20,
+			MaxInvitationCount:  1000,
+			DefaultWriteInLimit: 10,
+			MaxImportedBallots:  100_000,
+		},
+	}
+}
+
+// setting can be set by an env variable and a flag.
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	field func(c *Config) any
+}
+
+var settings = []setting{
+	{"SRV_ADDRESS", "addr", "address to listen on", func(c *Config) any { return &c.Server.Addr }},
+	{"SRV_READ_TIMEOUT", "read-timeout", "max time to read a request", func(c *Config) any { return &c.Server.ReadTimeout }},
+	{"SRV_READ_HEADER_TIMEOUT", "read-header-timeout", "max time to read the headers of a request", func(c *Config) any { return &c.Server.ReadHeaderTimeout }},
+	{"SRV_WRITE_TIMEOUT", "write-timeout", "max time to write a response", func(c *Config) any { return &c.Server.WriteTimeout }},
+	{"SRV_IDLE_TIMEOUT", "idle-timeout", "max time to keep idle connections open", func(c *Config) any { return &c.Server.IdleTimeout }},
+	{"SRV_DRAIN_DELAY", "drain-delay", "time to keep serving after failing readiness when shutting down", func(c *Config) any { return &c.Server.DrainDelay }},
+	{"SRV_SHUTDOWN_GRACE", "shutdown-grace", "time given to requests to finish when shutting down", func(c *Config) any { return &c.Server.ShutdownGrace }},
+	{"SRV_TLS_CERT_FILE", "tls-cert-file", "certificate to serve HTTPS with", func(c *Config) any { return &c.Server.TLSCertFile }},
+	{"SRV_TLS_KEY_FILE", "tls-key-file", "key of the HTTPS certificate", func(c *Config) any { return &c.Server.TLSKeyFile }},
+	{"LOG_LEVEL", "log-level", "minimum level logged: debug, info, warn or error", func(c *Config) any { return &c.Log.Level }},
+	{"LOG_FORMAT", "log-format", "log format: text or json", func(c *Config) any { return &c.Log.Format }},
+	{"LOG_BODY_LIMIT", "log-body-limit", "max bytes of bodies logged, 0 disables them", func(c *Config) any { return &c.Log.BodyLimit }},
+	{"LOG_REDACT_FIELDS", "log-redact-fields", "comma separated JSON fields redacted from logs", func(c *Config) any { return &c.Log.RedactFields }},
+	{"CORS_ORIGINS", "cors-origins", "comma separated origins allowed to call the API", func(c *Config) any { return &c.CORS.Origins }},
+	{"CORS_METHODS", "cors-methods", "comma separated methods allowed by CORS", func(c *Config) any { return &c.CORS.Methods }},
+	{"CORS_HEADERS", "cors-headers", "comma separated headers allowed by CORS", func(c *Config) any { return &c.CORS.Headers }},
+	{"CORS_ALLOW_CREDENTIALS", "cors-allow-credentials", "let browsers send credentials", func(c *Config) any { return &c.CORS.AllowCredentials }},
+	{"CORS_MAX_AGE", "cors-max-age", "how long browsers cache preflight responses", func(c *Config) any { return &c.CORS.MaxAge }},
+	{"RATE_LIMIT_ENABLED", "rate-limit-enabled", "limit the requests of each client", func(c *Config) any { return &c.RateLimit.Enabled }},
+	{"TRUSTED_PROXIES", "trusted-proxies", "comma separated IPs or CIDRs of proxies whose X-Forwarded-For is trusted", func(c *Config) any { return &c.RateLimit.TrustedProxies }},
+	{"MAX_LOGIN_FAILURES", "max-login-failures", "failed logins before the account is locked", func(c *Config) any { return &c.RateLimit.MaxLoginFailures }},
+	{"LOCKOUT_DURATION", "lockout-duration", "how long accounts stay locked", func(c *Config) any { return &c.RateLimit.LockoutDuration }},
+	{"FRONTEND_ENABLED", "frontend-enabled", "serve the embedded frontend", func(c *Config) any { return &c.Frontend.Enabled }},
+	{"API_BASE_URL", "api-base-url", "where the frontend calls the API, empty uses the same origin", func(c *Config) any { return &c.Frontend.ApiBaseUrl }},
+	{"STORAGE_PATH", "storage-path", "directory where polls are persisted", func(c *Config) any { return &c.StoragePath }},
+	{"MAX_BODY_BYTES", "max-body-bytes", "max size of request bodies", func(c *Config) any { return &c.Limits.MaxBodyBytes }},
+	{"MAX_INVITATION_COUNT", "max-invitation-count", "max invitation codes generated for a poll", func(c *Config) any { return &c.Limits.MaxInvitationCount }},
+	{"DEFAULT_WRITE_IN_LIMIT", "default-write-in-limit", "write-ins allowed when a poll doesn't set a limit", func(c *Config) any { return &c.Limits.DefaultWriteInLimit }},
+	{"MAX_IMPORTED_BALLOTS", "max-imported-ballots", "max ballots a poll import can have", func(c *Config) any { return &c.Limits.MaxImportedBallots }},
+}
+
+// LoadConfig merges the defaults, the config file, the env variables and the flags, in that order.
+// The config file is set with the -config flag or the CONFIG_FILE env variable.
+func LoadConfig(args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	config := DefaultConfig()
+
+	type flagValue struct {
+		setting setting
+		value   string
+	}
+	flagValues := make([]flagValue, 0)
+
+	flags := flag.NewFlagSet("rankpoll", flag.ContinueOnError)
+	configFile := flags.String("config", "", "JSON config file")
+	for _, s := range settings {
+		flags.Func(s.flag, fmt.Sprintf("%s (env %s)", s.usage, s.env), func(value string) error {
+			flagValues = append(flagValues, flagValue{setting: s, value: value})
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return config, err
+	}
+	if flags.NArg() > 0 {
+		return config, fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+
+	if *configFile == "" {
+		*configFile, _ = lookupEnv("CONFIG_FILE")
+	}
+	if *configFile != "" {
+		if err := loadConfigFile(&config, *configFile); err != nil {
+			return config, fmt.Errorf("config file %s: %w", *configFile, err)
+		}
+	}
+
+	for _, s := range settings {
+		if value, found := lookupEnv(s.env); found {
+			if err := parseSetting(s.field(&config), value); err != nil {
+				return config, fmt.Errorf("env %s: %w", s.env, err)
+			}
+		}
+	}
+
+	for _, f := range flagValues {
+		if err := parseSetting(f.setting.field(&config), f.value); err != nil {
+			return config, fmt.Errorf("flag -%s: %w", f.setting.flag, err)
+		}
+	}
+
+	return config, config.Validate()
+}
+
+// loadConfigFile decodes the file over the config, rejecting unknown keys.
+// Maps of the file replace the ones of the config instead of being merged with them.
+func loadConfigFile(config *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if strings.ToLower(filepath.Ext(path)) != ".json" {
+		return errors.New("only .json files are supported")
+	}
+
+	defaultRoutes := config.RateLimit.Routes
+	config.RateLimit.Routes = nil
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		return err
+	}
+
+	if config.RateLimit.Routes == nil {
+		config.RateLimit.Routes = defaultRoutes
+	}
+	return nil
+}
+
+// parseSetting parses the value of an env variable or flag into the field.
+func parseSetting(field any, value string) error {
+	switch f := field.(type) {
+	case *string:
+		*f = value
+	case *[]string:
+		*f = make([]string, 0)
+		for item := range strings.SplitSeq(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*f = append(*f, item)
+			}
+		}
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*f = b
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*f = n
+	case *int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		*f = n
+	case *uint:
+		n, err := strconv.ParseUint(value, 10, 0)
+		if err != nil {
+			return err
+		}
+		*f = uint(n)
+	case encoding.TextUnmarshaler:
+		return f.UnmarshalText([]byte(value))
+	default:
+		return fmt.Errorf("unsupported setting type %T", field)
+	}
+	return nil
+}
+
+// Validate returns all the invalid values of the config.
+func (c Config) Validate() error {
+	errs := make([]error, 0)
+
+	if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("Server.Addr: %w", err))
+	}
+
+	timeouts := []struct {
+		name  string
+		value Duration
+	}{
+		{"Server.ReadTimeout", c.Server.ReadTimeout},
+		{"Server.ReadHeaderTimeout", c.Server.ReadHeaderTimeout},
+		{"Server.WriteTimeout", c.Server.WriteTimeout},
+		{"Server.IdleTimeout", c.Server.IdleTimeout},
+		{"Server.DrainDelay", c.Server.DrainDelay},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value < 0 {
+			errs = append(errs, fmt.Errorf("%s: can't be negative", timeout.name))
+		}
+	}
+	if c.Server.ShutdownGrace <= 0 {
+		errs = append(errs, errors.New("Server.ShutdownGrace: must be positive"))
+	}
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		errs = append(errs, errors.New("Server.TLSCertFile: the certificate and key must be set together"))
+	}
+
+	if c.Log.Format != "text" && c.Log.Format != "json" {
+		errs = append(errs, fmt.Errorf("Log.Format: expected text or json but got %q", c.Log.Format))
+	}
+	if c.Log.BodyLimit < 0 {
+		errs = append(errs, errors.New("Log.BodyLimit: can't be negative"))
+	}
+
+	for _, origin := range c.CORS.Origins {
+		if err := validateOrigin(origin); err != nil {
+			errs = append(errs, fmt.Errorf("CORS.Origins: %w", err))
+		}
+	}
+	if c.CORS.AllowCredentials && slices.Contains(c.CORS.Origins, "*") {
+		errs = append(errs, errors.New("CORS.AllowCredentials: can't be used when any origin is allowed"))
+	}
+	for _, method := range c.CORS.Methods {
+		if !slices.Contains(validMethods, method) {
+			errs = append(errs, fmt.Errorf("CORS.Methods: unknown method %q", method))
+		}
+	}
+	if c.CORS.MaxAge < 0 {
+		errs = append(errs, errors.New("CORS.MaxAge: can't be negative"))
+	}
+
+	if _, err := parseTrustedProxies(c.RateLimit.TrustedProxies); err != nil {
+		errs = append(errs, fmt.Errorf("RateLimit.TrustedProxies: %w", err))
+	}
+	for route, limit := range c.RateLimit.Routes {
+		if !isMountedPath(route) {
+			errs = append(errs, fmt.Errorf("RateLimit.Routes: %s isn't a route of the API", route))
+		}
+		if limit.PerMinute <= 0 || limit.Burst == 0 {
+			errs = append(errs, fmt.Errorf("RateLimit.Routes: the limit of %s must be positive", route))
+		}
+	}
+	if c.RateLimit.MaxLoginFailures == 0 {
+		errs = append(errs, errors.New("RateLimit.MaxLoginFailures: must be positive"))
+	}
+	if c.RateLimit.LockoutDuration <= 0 {
+		errs = append(errs, errors.New("RateLimit.LockoutDuration: must be positive"))
+	}
+
+	if c.Frontend.ApiBaseUrl != "" {
+		u, err := url.Parse(c.Frontend.ApiBaseUrl)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" {
+			errs = append(errs, fmt.Errorf("Frontend.ApiBaseUrl: %q is not a URL like https://api.example.com", c.Frontend.ApiBaseUrl))
+		}
+	}
+
+	if c.StoragePath != "" {
+		if info, err := os.Stat(c.StoragePath); err != nil {
+			errs = append(errs, fmt.Errorf("StoragePath: %w", err))
+		} else if !info.IsDir() {
+			errs = append(errs, fmt.Errorf("StoragePath: %s is not a directory", c.StoragePath))
+		}
+	}
+
+	if c.Limits.MaxBodyBytes <= 0 {
+		errs = append(errs, errors.New("Limits.MaxBodyBytes: must be positive"))
+	}
+	if c.Limits.MaxInvitationCount == 0 {
+		errs = append(errs, errors.New("Limits.MaxInvitationCount: must be positive"))
+	}
+	if c.Limits.DefaultWriteInLimit == 0 {
+		errs = append(errs, errors.New("Limits.DefaultWriteInLimit: must be positive"))
+	}
+	if c.Limits.MaxImportedBallots == 0 {
+		errs = append(errs, errors.New("Limits.MaxImportedBallots: must be positive"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateOrigin accepts "*" or a scheme and host, where the host can start with "*." to match subdomains.
+func validateOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+
+	u, err := url.Parse(strings.Replace(origin, "://*.", "://wildcard.", 1))
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return fmt.Errorf("%q is not an origin like https://example.com", origin)
+	}
+	if strings.Contains(u.Host, "*") {
+		return fmt.Errorf("%q can only use a wildcard as its first label, like https://*.example.com", origin)
+	}
+	return nil
+}
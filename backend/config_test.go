@@ -0,0 +1,200 @@
+package main
+
+import (
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	writeFile := func(t *testing.T, name string, content string) string {
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write config file: %s\n", err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		doCheck func(t *testing.T)
+	}{
+		{
+			name: "Defaults",
+			doCheck: func(t *testing.T) {
+				config, err := LoadConfig(nil, mapEnv(nil))
+				if err != nil {
+					t.Fatalf("Expected defaults to be valid but got: %s\n", err)
+				}
+
+				if config.Server.Addr != "127.0.0.1:8080" {
+					t.Fatalf("Expected default address but got `%s`\n", config.Server.Addr)
+				}
+			},
+		},
+		{
+			name: "Flags override env which overrides file",
+			doCheck: func(t *testing.T) {
+				path := writeFile(t, "config.json", `{
+	"Server": {"Addr": "0.0.0.0:9000", "ShutdownGrace": "20s"},
+	"Log": {"Level": "debug", "Format": "json"},
+	"CORS": {"Origins": ["https://poll.example.com", "https://*.example.com"]}
+}`)
+				config, err := LoadConfig(
+					[]string{"-config", path, "-addr", ":7000"},
+					mapEnv(map[string]string{"SRV_ADDRESS": ":8000", "LOG_FORMAT": "text"}),
+				)
+				if err != nil {
+					t.Fatalf("Failed to load config: %s\n", err)
+				}
+
+				if config.Server.Addr != ":7000" {
+					t.Fatalf("Expected the flag address but got `%s`\n", config.Server.Addr)
+				}
+				if config.Log.Format != "text" {
+					t.Fatalf("Expected the env log format but got `%s`\n", config.Log.Format)
+				}
+				if config.Log.Level != slog.LevelDebug || time.Duration(config.Server.ShutdownGrace) != 20*time.Second {
+					t.Fatalf("Expected the file values but got: %+v\n", config)
+				}
+				if !slices.Equal(config.CORS.Origins, []string{"https://poll.example.com", "https://*.example.com"}) {
+					t.Fatalf("Expected the file origins but got: %v\n", config.CORS.Origins)
+				}
+			},
+		},
+		{
+			name: "JSON file",
+			doCheck: func(t *testing.T) {
+				path := writeFile(t, "config.json", `{"Limits": {"MaxInvitationCount": 5}}`)
+				config, err := LoadConfig(nil, mapEnv(map[string]string{"CONFIG_FILE": path}))
+				if err != nil {
+					t.Fatalf("Failed to load config: %s\n", err)
+				}
+
+				if config.Limits.MaxInvitationCount != 5 {
+					t.Fatalf("Expected 5 invitations but got %d\n", config.Limits.MaxInvitationCount)
+				}
+			},
+		},
+		{
+			name: "Example file",
+			doCheck: func(t *testing.T) {
+				config, err := LoadConfig([]string{"-config", "config.example.json"}, mapEnv(nil))
+				if err != nil {
+					t.Fatalf("Failed to load the example config: %s\n", err)
+				}
+
+				if !maps.Equal(config.RateLimit.Routes, DefaultConfig().RateLimit.Routes) {
+					t.Fatalf("Expected the example routes to be the default ones but got: %v\n", config.RateLimit.Routes)
+				}
+			},
+		},
+		{
+			name: "TOML file",
+			doCheck: func(t *testing.T) {
+				path := writeFile(t, "config.toml", "[Server]\nAddr = \":80\"\n")
+				_, err := LoadConfig([]string{"-config", path}, mapEnv(nil))
+				if err == nil {
+					t.Fatalf("Expected only JSON files to be accepted\n")
+				}
+			},
+		},
+		{
+			name: "File routes replace the default ones",
+			doCheck: func(t *testing.T) {
+				path := writeFile(t, "config.json", `{"RateLimit": {"Routes": {"/api/v1/polls": {"PerMinute": 5, "Burst": 1}}}}`)
+				config, err := LoadConfig([]string{"-config", path}, mapEnv(nil))
+				if err != nil {
+					t.Fatalf("Failed to load config: %s\n", err)
+				}
+
+				expected := map[string]RateLimit{"/api/v1/polls": {PerMinute: 5, Burst: 1}}
+				if !maps.Equal(config.RateLimit.Routes, expected) {
+					t.Fatalf("Expected only the file routes but got: %v\n", config.RateLimit.Routes)
+				}
+
+				config, err = LoadConfig(nil, mapEnv(nil))
+				if err != nil {
+					t.Fatalf("Failed to load config: %s\n", err)
+				}
+				if len(config.RateLimit.Routes) == 0 {
+					t.Fatalf("Expected the default routes without a file\n")
+				}
+			},
+		},
+		{
+			name: "Unknown keys",
+			doCheck: func(t *testing.T) {
+				path := writeFile(t, "config.json", `{"Server": {"Adress": ":80"}}`)
+				_, err := LoadConfig([]string{"-config", path}, mapEnv(nil))
+				if err == nil {
+					t.Fatalf("Expected the misspelled key to be rejected\n")
+				}
+			},
+		},
+		{
+			name: "Invalid values",
+			doCheck: func(t *testing.T) {
+				_, err := LoadConfig(
+					[]string{"-log-format", "xml", "-cors-origins", "example.com"},
+					mapEnv(map[string]string{"SRV_ADDRESS": "", "SRV_SHUTDOWN_GRACE": "0s"}),
+				)
+				if err == nil {
+					t.Fatalf("Expected invalid values to be rejected\n")
+				}
+
+				for _, field := range []string{"Server.Addr", "Server.ShutdownGrace", "Log.Format", "CORS.Origins"} {
+					if !strings.Contains(err.Error(), field) {
+						t.Fatalf("Expected %s to be reported but got: %s\n", field, err)
+					}
+				}
+			},
+		},
+		{
+			name: "Unknown rate limited routes",
+			doCheck: func(t *testing.T) {
+				path := writeFile(t, "config.json", `{"RateLimit": {"Routes": {"/api/v1/pols": {"PerMinute": 5, "Burst": 1}}}}`)
+				_, err := LoadConfig([]string{"-config", path}, mapEnv(nil))
+				if err == nil || !strings.Contains(err.Error(), "/api/v1/pols") {
+					t.Fatalf("Expected the misspelled route to be rejected but got: %v\n", err)
+				}
+			},
+		},
+		{
+			name: "Wildcard origins",
+			doCheck: func(t *testing.T) {
+				for _, origin := range []string{"https://*example.com", "https://poll.*.example.com", "https://*"} {
+					_, err := LoadConfig([]string{"-cors-origins", origin}, mapEnv(nil))
+					if err == nil || !strings.Contains(err.Error(), "CORS.Origins") {
+						t.Fatalf("Expected %s to be rejected but got: %v\n", origin, err)
+					}
+				}
+			},
+		},
+		{
+			name: "Unparseable env",
+			doCheck: func(t *testing.T) {
+				_, err := LoadConfig(nil, mapEnv(map[string]string{"SRV_READ_TIMEOUT": "ten seconds"}))
+				if err == nil || !strings.Contains(err.Error(), "SRV_READ_TIMEOUT") {
+					t.Fatalf("Expected the env variable to be reported but got: %v\n", err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, tt.doCheck)
+	}
+}
+
+func mapEnv(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, found := env[key]
+		return value, found
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ElrohirGT/RankPoll/response"
+)
+
+// Headers the frontend can read from responses.
+var corsExposedHeaders = []string{response.RequestIdHeader, "Content-Disposition", "Retry-After", "Deprecation", "Link"}
+
+// CORSMiddleware lets the configured origins call the API.
+// Preflight requests are only answered for routes of the router, others get a 404.
+func CORSMiddleware(config CORSConfig, router *http.ServeMux) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			requestedMethod := r.Header.Get("Access-Control-Request-Method")
+			isPreflight := r.Method == http.MethodOptions && origin != "" && requestedMethod != ""
+
+			w.Header().Add("Vary", "Origin")
+			if isPreflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			allowedOrigin, allowed := config.AllowedOrigin(origin)
+			if !allowed {
+				if isPreflight {
+					response.WriteError(w, r, response.NewError(http.StatusForbidden, response.CodeOriginNotAllowed, "Origin not allowed!", errors.New("the origin can't call the API")))
+					return
+				}
+
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
+			if config.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !isPreflight {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !isKnownRoute(router, r, requestedMethod) {
+				response.WriteError(w, r, response.NewError(http.StatusNotFound, response.CodeRouteNotFound, "Route not found!", errors.New("no route matches the preflight request")))
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(config.Methods, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(config.Headers, ", "))
+			if config.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(time.Duration(config.MaxAge).Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// isKnownRoute checks if the router has a route for the request made with the method.
+// The frontend catch-all doesn't count, it isn't meant to be called cross-origin.
+func isKnownRoute(router *http.ServeMux, r *http.Request, method string) bool {
+	withMethod := r.Clone(r.Context())
+	withMethod.Method = method
+	_, pattern := router.Handler(withMethod)
+	return pattern != "" && pattern != "/"
+}
+
+// AllowedOrigin returns the value of Access-Control-Allow-Origin for the origin,
+// false if it isn't allowed.
+func (c CORSConfig) AllowedOrigin(origin string) (string, bool) {
+	if origin == "" {
+		return "", false
+	}
+
+	for _, pattern := range c.Origins {
+		if pattern == "*" {
+			return "*", true
+		}
+
+		if originMatches(strings.TrimSuffix(pattern, "/"), origin) {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+// originMatches compares the scheme and host of the origin,
+// a pattern host starting with "*." matches any of its subdomains but not the domain itself.
+func originMatches(pattern string, origin string) bool {
+	patternScheme, patternHost, _ := strings.Cut(strings.ToLower(pattern), "://")
+	originScheme, originHost, _ := strings.Cut(strings.ToLower(origin), "://")
+	if patternScheme != originScheme {
+		return false
+	}
+
+	if domain, isWildcard := strings.CutPrefix(patternHost, "*."); isWildcard {
+		return strings.HasSuffix(originHost, "."+domain) && len(originHost) > len(domain)+1
+	}
+	return patternHost == originHost
+}
+
+// validMethods are the methods CORS can allow.
+var validMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
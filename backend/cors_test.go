@@ -0,0 +1,124 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	config := DefaultConfig().CORS
+	config.Origins = []string{"https://poll.example.com", "https://*.rankpoll.dev"}
+
+	router := http.NewServeMux()
+	MountHandlers(router)
+	handler := CORSMiddleware(config, router)(router)
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		origin        string
+		requestMethod string
+		status        int
+		allowOrigin   string
+	}{
+		{
+			name:          "Preflight from allowed origin",
+			method:        http.MethodOptions,
+			path:          "/api/poll/import",
+			origin:        "https://poll.example.com",
+			requestMethod: http.MethodPost,
+			status:        http.StatusNoContent,
+			allowOrigin:   "https://poll.example.com",
+		},
+		{
+			name:          "Preflight from wildcard subdomain",
+			method:        http.MethodOptions,
+			path:          "/api/user",
+			origin:        "https://staging.rankpoll.dev",
+			requestMethod: http.MethodPost,
+			status:        http.StatusNoContent,
+			allowOrigin:   "https://staging.rankpoll.dev",
+		},
+		{
+			name:          "Wildcard doesn't match the domain itself",
+			method:        http.MethodOptions,
+			path:          "/api/user",
+			origin:        "https://rankpoll.dev",
+			requestMethod: http.MethodPost,
+			status:        http.StatusForbidden,
+		},
+		{
+			name:          "Wildcard only matches whole labels",
+			method:        http.MethodOptions,
+			path:          "/api/user",
+			origin:        "https://evilrankpoll.dev",
+			requestMethod: http.MethodPost,
+			status:        http.StatusForbidden,
+		},
+		{
+			name:          "Preflight from unknown origin",
+			method:        http.MethodOptions,
+			path:          "/api/user",
+			origin:        "https://evil.example.com",
+			requestMethod: http.MethodPost,
+			status:        http.StatusForbidden,
+		},
+		{
+			name:          "Preflight for unknown route",
+			method:        http.MethodOptions,
+			path:          "/api/unknown",
+			origin:        "https://poll.example.com",
+			requestMethod: http.MethodPost,
+			status:        http.StatusNotFound,
+			allowOrigin:   "https://poll.example.com",
+		},
+		{
+			name:          "Preflight for wrong method",
+			method:        http.MethodOptions,
+			path:          "/api/poll/1234/export/csv",
+			origin:        "https://poll.example.com",
+			requestMethod: http.MethodPost,
+			status:        http.StatusNotFound,
+			allowOrigin:   "https://poll.example.com",
+		},
+		{
+			name:        "Simple request echoes origin",
+			method:      http.MethodGet,
+			path:        "/healthz",
+			origin:      "https://poll.example.com",
+			status:      http.StatusOK,
+			allowOrigin: "https://poll.example.com",
+		},
+		{
+			name:   "Simple request from unknown origin",
+			method: http.MethodGet,
+			path:   "/healthz",
+			origin: "https://evil.example.com",
+			status: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.requestMethod != "" {
+				req.Header.Set("Access-Control-Request-Method", tt.requestMethod)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("Expected %d but got (%d): %s\n", tt.status, w.Code, w.Body)
+			}
+
+			allowOrigin := w.Header().Get("Access-Control-Allow-Origin")
+			if allowOrigin != tt.allowOrigin {
+				t.Fatalf("Expected allowed origin `%s` but got `%s`\n", tt.allowOrigin, allowOrigin)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ElrohirGT/RankPoll/response"
+	"github.com/google/uuid"
+)
+
+// MediaEventStream is the media type of Server-Sent Events.
+const MediaEventStream = "text/event-stream"
+
+// Comments are sent this often so proxies don't close idle streams.
+const streamKeepAlive = 30 * time.Second
+
+// pollEvents notifies the streams of a poll when it changes.
+type pollEvents struct {
+	lock        sync.Mutex
+	subscribers map[string]map[chan struct{}]bool
+	closed      bool
+}
+
+var PollEvents = newPollEvents()
+
+func newPollEvents() *pollEvents {
+	return &pollEvents{subscribers: make(map[string]map[chan struct{}]bool)}
+}
+
+// Subscribe returns a channel that receives a value when the poll changes and is closed on shutdown.
+// Call unsubscribe once the stream ends.
+func (e *pollEvents) Subscribe(pollId string) (updates <-chan struct{}, unsubscribe func()) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	ch := make(chan struct{}, 1)
+	if e.closed {
+		close(ch)
+		return ch, func() {}
+	}
+
+	if e.subscribers[pollId] == nil {
+		e.subscribers[pollId] = make(map[chan struct{}]bool)
+	}
+	e.subscribers[pollId][ch] = true
+
+	return ch, func() {
+		e.lock.Lock()
+		defer e.lock.Unlock()
+
+		delete(e.subscribers[pollId], ch)
+		if len(e.subscribers[pollId]) == 0 {
+			delete(e.subscribers, pollId)
+		}
+	}
+}
+
+// Publish notifies the subscribers of the poll without waiting for them,
+// changes made while a stream is still sending the last one are sent together.
+func (e *pollEvents) Publish(pollId string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	for ch := range e.subscribers[pollId] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Close ends every stream, so they don't hold the server on shutdown.
+func (e *pollEvents) Close() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.closed = true
+	for _, subscribers := range e.subscribers {
+		for ch := range subscribers {
+			close(ch)
+		}
+	}
+	clear(e.subscribers)
+}
+
+// StreamPoll sends the poll as Server-Sent Events, a "poll" event when connecting and each time it changes.
+// The stream ends after the poll closes with a last event that has its summary,
+// or with a "deleted" event if the poll is deleted.
+func StreamPoll(w http.ResponseWriter, r *http.Request) error {
+	pollId, err := uuid.Parse(r.PathValue("pollId"))
+	if err != nil {
+		return response.NewError(http.StatusNotFound, response.CodePollNotFound, "Poll not found!", err)
+	}
+
+	updates, unsubscribe := PollEvents.Subscribe(pollId.String())
+	defer unsubscribe()
+
+	pollInfo, found := lookupPoll(r.Context(), pollId)
+	if !found {
+		return response.NewError(http.StatusNotFound, response.CodePollNotFound, "Poll not found!", errors.New("the poll was not found"))
+	}
+
+	rc := http.NewResponseController(w)
+	// Streams last longer than the write timeout of the server.
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", MediaEventStream)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	for {
+		if !found {
+			return writeEvent(w, rc, "deleted", struct{ PollId uuid.UUID }{pollId})
+		}
+
+		if err := writeEvent(w, rc, "poll", toPosixTime(pollInfo)); err != nil {
+			return err
+		}
+		if pollInfo.Summary != nil {
+			return nil
+		}
+
+		if keepStreaming, err := waitForUpdate(w, r, rc, updates, pollInfo.ValidUntil); !keepStreaming {
+			return err
+		}
+
+		pollInfo, found = lookupPoll(r.Context(), pollId)
+	}
+}
+
+// waitForUpdate blocks until the poll changes or closes at closesAt, sending keep-alive comments meanwhile.
+// It returns false when the stream must end, because the client left or the server is shutting down.
+func waitForUpdate(w http.ResponseWriter, r *http.Request, rc *http.ResponseController, updates <-chan struct{}, closesAt time.Time) (bool, error) {
+	closing := time.NewTimer(time.Until(closesAt))
+	defer closing.Stop()
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return false, nil
+		case _, open := <-updates:
+			return open, nil
+		case <-closing.C:
+			return true, nil
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return false, err
+			}
+			_ = rc.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, rc *http.ResponseController, event string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamPoll(t *testing.T) {
+	defer CleanGlobalState()
+
+	router := http.NewServeMux()
+	MountHandlers(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	post := func(path string, body any) *http.Response {
+		var reqBody bytes.Buffer
+		_ = json.NewEncoder(&reqBody).Encode(body)
+		resp, err := http.Post(server.URL+path, "application/json", &reqBody)
+		if err != nil {
+			t.Fatalf("Failed to make request: %s\n", err)
+		}
+		return resp
+	}
+
+	resp := post("/api/v1/polls", CreatePollRequest{
+		Title:           "Lenguaje",
+		PollingDuration: 5 * time.Second,
+		PollOptions:     []string{"Español", "Alemán"},
+	})
+	var pollResponse CreatePollResponse
+	if err := json.NewDecoder(resp.Body).Decode(&pollResponse); err != nil {
+		t.Fatalf("Failed to decode response: %s\n", err)
+	}
+	pollPath := "/api/v1/polls/" + pollResponse.PollId.String()
+
+	stream, err := http.Get(server.URL + pollPath + "/events")
+	if err != nil {
+		t.Fatalf("Failed to open stream: %s\n", err)
+	}
+	defer stream.Body.Close()
+
+	if contentType := stream.Header.Get("Content-Type"); contentType != MediaEventStream {
+		t.Fatalf("Expected %s but got %s\n", MediaEventStream, contentType)
+	}
+
+	events := bufio.NewReader(stream.Body)
+	nextPoll := func() Room[int64] {
+		var event, data string
+		for {
+			line, err := events.ReadString('\n')
+			if err != nil {
+				t.Fatalf("Failed to read event: %s\n", err)
+			}
+
+			line = strings.TrimSuffix(line, "\n")
+			if line == "" && data != "" {
+				break
+			}
+			if name, found := strings.CutPrefix(line, "event: "); found {
+				event = name
+			}
+			if value, found := strings.CutPrefix(line, "data: "); found {
+				data = value
+			}
+		}
+
+		if event != "poll" {
+			t.Fatalf("Expected a poll event but got %s\n", event)
+		}
+
+		var roomInfo Room[int64]
+		if err := json.Unmarshal([]byte(data), &roomInfo); err != nil {
+			t.Fatalf("Failed to decode event: %s\n", err)
+		}
+		return roomInfo
+	}
+
+	if roomInfo := nextPoll(); len(roomInfo.Votes) != 0 {
+		t.Fatalf("The poll shouldn't have votes yet! %#v\n", roomInfo.Votes)
+	}
+
+	resp = post(pollPath+"/votes", VoteInPollRequest{
+		Username: "Tyron",
+		Options:  map[string]uint{"Español": 1, "Alemán": 2},
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Failed to vote (%d)\n", resp.StatusCode)
+	}
+
+	if roomInfo := nextPoll(); len(roomInfo.Votes) != 1 {
+		t.Fatalf("The vote wasn't streamed! %#v\n", roomInfo.Votes)
+	}
+}
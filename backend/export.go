@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ElrohirGT/RankPoll/response"
+	"github.com/google/uuid"
+)
+
+// writeBLT writes the ballots of the room in the BLT election format.
+// Candidates are numbered following the order of room.Options,
+// equally ranked options are joined with "=" and unranked options are left out.
+// If useIds is true candidates are named by their option id instead of their label.
+func writeBLT(w io.Writer, room Room[time.Time], useIds bool) error {
+	buf := bufio.NewWriter(w)
+
+	candidates := make(map[uuid.UUID]int, len(room.Options))
+	for i, opt := range room.Options {
+		candidates[opt.Id] = i + 1
+	}
+
+	fmt.Fprintf(buf, "%d 1\n", len(room.Options))
+
+	for _, vote := range room.Ballots() {
+		ranking := sortedRanking(vote)
+
+		buf.WriteString("1")
+		for i, r := range ranking {
+			if i > 0 && r.Position == ranking[i-1].Position {
+				buf.WriteString("=")
+			} else {
+				buf.WriteString(" ")
+			}
+			fmt.Fprintf(buf, "%d", candidates[r.OptionId])
+		}
+		buf.WriteString(" 0\n")
+	}
+	buf.WriteString("0\n")
+
+	for _, opt := range room.Options {
+		name := opt.Label
+		if useIds {
+			name = opt.Id.String()
+		}
+		fmt.Fprintf(buf, "%s\n", bltQuote(name))
+	}
+	fmt.Fprintf(buf, "%s\n", bltQuote(room.Title))
+
+	return buf.Flush()
+}
+
+// bltQuote quotes a BLT string, the format doesn't support escaping quotes.
+func bltQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "'") + `"`
+}
+
+func sortedRanking(vote Vote) []Rank {
+	ranking := slices.Clone(vote.Ranking)
+	slices.SortStableFunc(ranking, func(a, b Rank) int {
+		return int(a.Position) - int(b.Position)
+	})
+	return ranking
+}
+
+// ballotsTable has a row for each ballot with a column for the rank of each option.
+// Unranked options are left empty, the Voter column is only added if the ballot isn't secret.
+// It uses the same layout ImportCSV reads.
+func ballotsTable(room Room[time.Time]) response.Table {
+	header := make([]string, 0, len(room.Options)+1)
+	if !room.SecretBallot {
+		header = append(header, "Voter")
+	}
+	for _, opt := range room.Options {
+		header = append(header, opt.Label)
+	}
+	table := response.Table{header}
+
+	for _, vote := range room.Ballots() {
+		row := make([]string, 0, len(header))
+		if !room.SecretBallot {
+			row = append(row, vote.Username)
+		}
+
+		for _, opt := range room.Options {
+			cell := ""
+			for _, r := range vote.Ranking {
+				if r.OptionId == opt.Id {
+					cell = strconv.FormatUint(uint64(r.Position), 10)
+				}
+			}
+			row = append(row, cell)
+		}
+		table = append(table, row)
+	}
+
+	return table
+}
+
+// roundsTable has a row for each round of the summary with a column for the votes of each option.
+func roundsTable(room Room[time.Time]) response.Table {
+	header := []string{"Round", "Reason", "Threshold", "ExhaustedBallots"}
+	for _, opt := range room.Options {
+		header = append(header, opt.Label)
+	}
+	table := response.Table{header}
+
+	for _, round := range room.Summary.Rounds {
+		row := []string{
+			strconv.FormatUint(uint64(round.Number), 10),
+			string(round.Reason),
+			strconv.FormatUint(uint64(round.Threshold), 10),
+			strconv.FormatUint(uint64(round.ExhaustedBallots), 10),
+		}
+
+		for _, opt := range room.Options {
+			cell := ""
+			if count, found := round.Tally[opt.Id.String()]; found {
+				cell = strconv.FormatUint(uint64(count), 10)
+			}
+			row = append(row, cell)
+		}
+		table = append(table, row)
+	}
+
+	return table
+}
+
+// closedRoomForExport finds the poll of the request, computing its summary if needed.
+// Only closed polls can be exported so ballots can't leak partial results.
+// Exports that tell who cast each ballot are only for the owner of the poll,
+// who authenticates with HTTP Basic credentials. Anyone can download the rest to verify the results.
+func closedRoomForExport(w http.ResponseWriter, r *http.Request, withVoters bool) (Room[time.Time], error) {
+	now := time.Now()
+
+	pollId, err := uuid.Parse(r.PathValue("pollId"))
+	if err != nil {
+		return Room[time.Time]{}, response.NewError(http.StatusNotFound, response.CodePollNotFound, "Poll not found!", err)
+	}
+
+	GlobalState.Lock.Lock()
+	defer GlobalState.Lock.Unlock()
+
+	roomInfo, found := GlobalState.Rooms[pollId.String()]
+	if !found {
+		return Room[time.Time]{}, response.NewError(http.StatusNotFound, response.CodePollNotFound, "Poll not found!", errors.New("the poll was not found"))
+	}
+
+	if withVoters && !roomInfo.SecretBallot && roomInfo.Owner != "" {
+		if err := checkOwner(w, r, roomInfo, "Only the owner can export the voters of the poll!"); err != nil {
+			return Room[time.Time]{}, err
+		}
+	}
+
+	if !now.After(roomInfo.ValidUntil) {
+		return Room[time.Time]{}, response.NewError(http.StatusConflict, response.CodePollOpen, "The poll hasn't ended!", errors.New("only closed polls can be exported"))
+	}
+
+	if roomInfo.Summary == nil {
+		computeSummary(r.Context(), &roomInfo)
+		GlobalState.Rooms[pollId.String()] = roomInfo
+	}
+
+	return roomInfo, nil
+}
+
+// sendExport sends the body as an attachment, always as the media type of the export.
+func sendExport(w http.ResponseWriter, body any, mediaType string, filename string) error {
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	return response.NewResponseBuilder(http.StatusOK).
+		SetBody(body).
+		SendAs(w, mediaType)
+}
+
+// ExportBLT sends the ballots of a closed poll as a BLT file, which doesn't have the voters.
+// Use ?names=ids to name the candidates by option id instead of label.
+func ExportBLT(w http.ResponseWriter, r *http.Request) error {
+	roomInfo, err := closedRoomForExport(w, r, false)
+	if err != nil {
+		return err
+	}
+
+	var blt strings.Builder
+	_ = writeBLT(&blt, roomInfo, r.URL.Query().Get("names") == "ids")
+	return sendExport(w, blt.String(), response.MediaText, fmt.Sprintf("%s.blt", roomInfo.Id))
+}
+
+// ExportCSV sends the ballots of a closed poll as CSV.
+// Use ?data=rounds to get the tallies of each round instead.
+func ExportCSV(w http.ResponseWriter, r *http.Request) error {
+	rounds := r.URL.Query().Get("data") == "rounds"
+	roomInfo, err := closedRoomForExport(w, r, !rounds)
+	if err != nil {
+		return err
+	}
+
+	if rounds {
+		return sendExport(w, roundsTable(roomInfo), response.MediaCSV, fmt.Sprintf("%s-rounds.csv", roomInfo.Id))
+	}
+	return sendExport(w, ballotsTable(roomInfo), response.MediaCSV, fmt.Sprintf("%s-ballots.csv", roomInfo.Id))
+}
+
+// ExportJSONLines sends the ballots of a closed poll as JSON Lines.
+// Use ?data=rounds to get the transcript of each round instead.
+func ExportJSONLines(w http.ResponseWriter, r *http.Request) error {
+	rounds := r.URL.Query().Get("data") == "rounds"
+	roomInfo, err := closedRoomForExport(w, r, !rounds)
+	if err != nil {
+		return err
+	}
+
+	if rounds {
+		return sendExport(w, roomInfo.Summary.Rounds, response.MediaJSONLines, fmt.Sprintf("%s-rounds.jsonl", roomInfo.Id))
+	}
+	return sendExport(w, roomInfo.Ballots(), response.MediaJSONLines, fmt.Sprintf("%s-ballots.jsonl", roomInfo.Id))
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ElrohirGT/RankPoll/response"
+	"github.com/google/uuid"
+)
+
+func TestWriteBLT(t *testing.T) {
+	teacher := Option{Id: uuid.New(), Label: "Teacher"}
+	doctor := Option{Id: uuid.New(), Label: "Doctor"}
+	plumber := Option{Id: uuid.New(), Label: "Plumber"}
+	room := Room[time.Time]{
+		Title:   `Favorite "Profesion"?`,
+		Options: []Option{teacher, doctor, plumber},
+		Votes: map[string]Vote{
+			"Tyron": {Username: "Tyron", Ranking: []Rank{
+				{OptionId: teacher.Id, Position: 2},
+				{OptionId: doctor.Id, Position: 1},
+				{OptionId: plumber.Id, Position: 3},
+			}},
+			"Yuniqua": {Username: "Yuniqua", Ranking: []Rank{
+				{OptionId: teacher.Id, Position: 1},
+				{OptionId: doctor.Id, Position: 2},
+				{OptionId: plumber.Id, Position: 1},
+			}},
+		},
+	}
+
+	var out strings.Builder
+	err := writeBLT(&out, room, false)
+	if err != nil {
+		t.Fatalf("Failed to write BLT: %s\n", err)
+	}
+
+	expected := `3 1
+1 2 1 3 0
+1 1=3 2 0
+0
+"Teacher"
+"Doctor"
+"Plumber"
+"Favorite 'Profesion'?"
+`
+	if out.String() != expected {
+		t.Fatalf("BLT doesn't match!\nExpected:\n%s\nGot:\n%s", expected, out.String())
+	}
+}
+
+func TestBallotsTable(t *testing.T) {
+	teacher := Option{Id: uuid.New(), Label: "Teacher"}
+	doctor := Option{Id: uuid.New(), Label: "Doctor"}
+	room := Room[time.Time]{
+		Title:   "Favorite Profesion?",
+		Options: []Option{teacher, doctor},
+		Votes: map[string]Vote{
+			"Tyron": {Username: "Tyron", Ranking: []Rank{
+				{OptionId: teacher.Id, Position: 2},
+				{OptionId: doctor.Id, Position: 1},
+			}},
+			"Yuniqua": {Username: "Yuniqua", Ranking: []Rank{
+				{OptionId: teacher.Id, Position: 1},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		secret   bool
+		expected string
+	}{
+		{
+			name:     "Public ballot",
+			expected: "Voter,Teacher,Doctor\nTyron,2,1\nYuniqua,1,\n",
+		},
+		{
+			name:   "Secret ballot",
+			secret: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room.SecretBallot = tt.secret
+
+			var out strings.Builder
+			err := csv.NewWriter(&out).WriteAll(ballotsTable(room))
+			if err != nil {
+				t.Fatalf("Failed to write CSV: %s\n", err)
+			}
+
+			if tt.expected != "" && out.String() != tt.expected {
+				t.Fatalf("CSV doesn't match!\nExpected:\n%s\nGot:\n%s", tt.expected, out.String())
+			}
+
+			if tt.secret && strings.Contains(out.String(), "Tyron") {
+				t.Fatalf("Secret ballot leaks voters!\n%s", out.String())
+			}
+
+			poll, errs, err := parseCSV(strings.NewReader(out.String()), testImportLimits)
+			if err != nil || len(errs) > 0 || len(poll.Ballots) != 2 {
+				t.Fatalf("Exported CSV can't be imported back! %v %#v\n", err, errs)
+			}
+		})
+	}
+}
+
+func TestExportAccess(t *testing.T) {
+	defer CleanGlobalState()
+
+	GlobalState.Users["Owner"] = "12345"
+	GlobalState.Users["Tyron"] = "54321"
+	newRoom := func(owner string, secret bool) uuid.UUID {
+		pollId := uuid.New()
+		GlobalState.Rooms[pollId.String()] = Room[time.Time]{
+			Id:           pollId,
+			Owner:        owner,
+			Options:      []Option{{Id: uuid.New(), Label: "Teacher"}, {Id: uuid.New(), Label: "Doctor"}},
+			SecretBallot: secret,
+			Votes:        map[string]Vote{},
+			ValidUntil:   time.Now().Add(-time.Second),
+		}
+		return pollId
+	}
+	owned := newRoom("Owner", false)
+	secret := newRoom("Owner", true)
+	ownerless := newRoom("", false)
+
+	tests := []struct {
+		name     string
+		handler  response.HandlerFunc
+		pollId   uuid.UUID
+		query    string
+		username string
+		password string
+		status   int
+	}{
+		{name: "Owner can export voters", handler: ExportCSV, pollId: owned, username: "Owner", password: "12345", status: http.StatusOK},
+		{name: "Wrong password", handler: ExportCSV, pollId: owned, username: "Owner", password: "54321", status: http.StatusUnauthorized},
+		{name: "Another user", handler: ExportCSV, pollId: owned, username: "Tyron", password: "54321", status: http.StatusForbidden},
+		{name: "No credentials", handler: ExportJSONLines, pollId: owned, status: http.StatusUnauthorized},
+		{name: "Rounds are public", handler: ExportCSV, pollId: owned, query: "?data=rounds", status: http.StatusOK},
+		{name: "BLT is public", handler: ExportBLT, pollId: owned, status: http.StatusOK},
+		{name: "Secret ballots are public", handler: ExportJSONLines, pollId: secret, status: http.StatusOK},
+		{name: "Ownerless polls are public", handler: ExportCSV, pollId: ownerless, status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/"+tt.query, nil)
+			req.SetPathValue("pollId", tt.pollId.String())
+			if tt.username != "" {
+				req.SetBasicAuth(tt.username, tt.password)
+			}
+
+			w := httptest.NewRecorder()
+			response.Handle(tt.handler).ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("Expected %d but got (%d): %s\n", tt.status, w.Code, w.Body)
+			}
+
+			if tt.status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Fatalf("Expected the client to be asked for credentials\n")
+			}
+		})
+	}
+}
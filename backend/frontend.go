@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/ElrohirGT/RankPoll/response"
+)
+
+// The frontend is built into web/dist by `pnpm build`, binaries built before that serve no frontend.
+//
+//go:embed all:web
+var webFiles embed.FS
+
+// FrontendHandler serves the embedded frontend.
+// Paths that aren't files are answered with index.html so the Elm router can handle them.
+func FrontendHandler(config FrontendConfig) http.Handler {
+	dist, _ := fs.Sub(webFiles, "web/dist")
+	return frontendHandler(dist, config)
+}
+
+func frontendHandler(dist fs.FS, config FrontendConfig) http.Handler {
+	files := http.FileServerFS(dist)
+	index, indexErr := injectFrontendConfig(dist, config)
+
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			return response.NewError(http.StatusNotFound, response.CodeRouteNotFound, "Route not found!", errors.New("no API route matches the request"))
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			return response.NewError(http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, "Method not allowed!", errors.New("the frontend can only be fetched"))
+		}
+		if indexErr != nil {
+			return response.NewError(http.StatusNotFound, response.CodeRouteNotFound, "Frontend not found!", errors.New("the frontend wasn't built into this binary"))
+		}
+
+		name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+		if info, err := fs.Stat(dist, name); err == nil && !info.IsDir() && name != "index.html" {
+			// Vite adds a hash to the names of the assets it builds.
+			if strings.HasPrefix(name, "assets/") {
+				w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+			}
+			files.ServeHTTP(w, r)
+			return nil
+		}
+
+		if path.Ext(name) != "" {
+			return response.NewError(http.StatusNotFound, response.CodeRouteNotFound, "File not found!", errors.New("the frontend has no such file"))
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		_, err := w.Write(index)
+		return err
+	})
+}
+
+// injectFrontendConfig adds the config to index.html as window.RANKPOLL_CONFIG, read by main.js.
+func injectFrontendConfig(dist fs.FS, config FrontendConfig) ([]byte, error) {
+	index, err := fs.ReadFile(dist, "index.html")
+	if err != nil {
+		return nil, err
+	}
+
+	// json.Marshal escapes <, > and &, so the values can't close the script tag.
+	configJson, err := json.Marshal(struct{ ApiBaseUrl string }{config.ApiBaseUrl})
+	if err != nil {
+		return nil, err
+	}
+
+	script := []byte("<script>window.RANKPOLL_CONFIG = " + string(configJson) + ";</script>\n")
+	headEnd := bytes.Index(index, []byte("</head>"))
+	if headEnd < 0 {
+		return append(script, index...), nil
+	}
+	return bytes.Join([][]byte{index[:headEnd], script, index[headEnd:]}, nil), nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFrontendHandler(t *testing.T) {
+	dist := fstest.MapFS{
+		"index.html":        {Data: []byte("<html><head><title>RankPoll</title></head><body></body></html>")},
+		"assets/index-1.js": {Data: []byte("console.log('RankPoll')")},
+		"vite.svg":          {Data: []byte("<svg></svg>")},
+	}
+	handler := frontendHandler(dist, FrontendConfig{ApiBaseUrl: "https://api.example.com"})
+
+	tests := []struct {
+		name     string
+		path     string
+		status   int
+		contains string
+	}{
+		{name: "Index", path: "/", status: http.StatusOK, contains: `window.RANKPOLL_CONFIG = {"ApiBaseUrl":"https://api.example.com"};</script>`},
+		{name: "Client route", path: "/poll/1234", status: http.StatusOK, contains: "RANKPOLL_CONFIG"},
+		{name: "Asset", path: "/assets/index-1.js", status: http.StatusOK, contains: "console.log"},
+		{name: "Missing asset", path: "/assets/missing.js", status: http.StatusNotFound},
+		{name: "Unknown API route", path: "/api/unknown", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("Expected %d but got (%d): %s\n", tt.status, w.Code, w.Body)
+			}
+
+			if !strings.Contains(w.Body.String(), tt.contains) {
+				t.Fatalf("Expected the body to contain `%s` but got: %s\n", tt.contains, w.Body)
+			}
+		})
+	}
+}
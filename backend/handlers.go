@@ -1,306 +1,663 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/ElrohirGT/RankPoll/response"
 	"github.com/google/uuid"
 )
 
-type CreateOrLoginUserRequest struct {
-	Username string
-	Password string
-}
-
-type CreateOrLoginUserResponse struct {
-	Msg string
-}
-
-func CreateOrLoginUser(w http.ResponseWriter, r *http.Request) {
+func CreateOrLoginUser(w http.ResponseWriter, r *http.Request) error {
 	var req CreateOrLoginUserRequest
-	err := json.NewDecoder(r.Body).Decode(&req)
-	if err != nil {
-		_ = response.NewResponseBuilder(http.StatusBadRequest).
-			SetError("Invalid object received!", err).
-			SendAsJSON(w)
-		return
+	if err := response.DecodeJSON(r, &req); err != nil {
+		return err
+	}
+	if err := limitByUsername(w, r, req.Username); err != nil {
+		return err
 	}
 
 	GlobalState.Lock.Lock()
 	defer GlobalState.Lock.Unlock()
 
 	var msg string
-	if password, found := GlobalState.Users[req.Username]; !found {
+	if _, found := GlobalState.Users[req.Username]; !found {
 		GlobalState.Users[req.Username] = req.Password
 		msg = fmt.Sprintf("Registered %s user!", req.Username)
 	} else {
-		if password != req.Password {
-			_ = response.NewResponseBuilder(http.StatusBadRequest).
-				SetError("Invalid credentials", errors.New("password/username don't match")).
-				SendAsJSON(w)
-			return
+		ok, err := checkPassword(w, r, req.Username, req.Password)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return response.NewError(http.StatusBadRequest, response.CodeInvalidCredentials, "Invalid credentials", errors.New("password/username don't match"))
 		}
 		msg = fmt.Sprintf("User %s logging in!", req.Username)
 	}
-	log.Println(msg)
+	response.Logger(r.Context()).Info(msg, "username", req.Username)
 
-	_ = response.NewResponseBuilder(http.StatusOK).
+	return response.NewResponseBuilder(http.StatusOK).
 		SetBody(CreateOrLoginUserResponse{Msg: msg}).
-		SendAsJSON(w)
+		Send(w, r)
 }
 
-type CreatePollRequest struct {
-	Title           string
-	PollOptions     []string
-	PollingDuration time.Duration
-}
-type CreatePollResponse struct {
-	PollId uuid.UUID
-	Msg    string
-}
-
-func CreatePoll(w http.ResponseWriter, r *http.Request) {
+func CreatePoll(w http.ResponseWriter, r *http.Request) error {
 	var req CreatePollRequest
-	err := json.NewDecoder(r.Body).Decode(&req)
-	if err != nil {
-		_ = response.NewResponseBuilder(http.StatusBadRequest).
-			SetError("Invalid object received!", err).
-			SendAsJSON(w)
-		return
+	if err := response.DecodeJSON(r, &req); err != nil {
+		return err
+	}
+	if err := limitByUsername(w, r, req.Username); err != nil {
+		return err
+	}
+
+	optionReqs := req.Options
+	if len(optionReqs) == 0 {
+		for _, label := range req.PollOptions {
+			optionReqs = append(optionReqs, PollOptionRequest{Label: label})
+		}
 	}
 
-	if len(req.PollOptions) == 0 {
-		_ = response.NewResponseBuilder(http.StatusBadRequest).
-			SetError("Invalid option count!", errors.New("can't have a poll with 0 options")).
-			SendAsJSON(w)
-		return
+	if len(optionReqs) == 0 {
+		return response.NewError(http.StatusBadRequest, response.CodeInvalidOptionCount, "Invalid option count!", errors.New("can't have a poll with 0 options")).WithField("Options")
 	}
 
-	if len(req.PollOptions) == 1 {
-		_ = response.NewResponseBuilder(http.StatusBadRequest).
-			SetError("Invalid option count!", errors.New("can't have a poll with only 1 option")).
-			SendAsJSON(w)
-		return
+	if len(optionReqs) == 1 {
+		return response.NewError(http.StatusBadRequest, response.CodeInvalidOptionCount, "Invalid option count!", errors.New("can't have a poll with only 1 option")).WithField("Options")
+	}
+
+	options := make([]Option, 0, len(optionReqs))
+	labels := make(map[string]bool, len(optionReqs))
+	for i, opt := range optionReqs {
+		if opt.Label == "" {
+			return response.NewError(http.StatusBadRequest, response.CodeInvalidOption, "Invalid option!", errors.New("an option can't have an empty label")).WithField(fmt.Sprintf("Options[%d].Label", i))
+		}
+
+		if labels[opt.Label] {
+			return response.NewError(http.StatusBadRequest, response.CodeInvalidOption, "Invalid option!", fmt.Errorf("option %s is repeated", opt.Label)).WithField(fmt.Sprintf("Options[%d].Label", i))
+		}
+		labels[opt.Label] = true
+
+		options = append(options, Option{
+			Id:          uuid.New(),
+			Label:       opt.Label,
+			Description: opt.Description,
+			Link:        opt.Link,
+		})
+	}
+
+	if req.AllowWriteIns && req.Username == "" {
+		return response.NewError(http.StatusBadRequest, response.CodeInvalidSettings, "Write-ins need an owner!", errors.New("a poll with write-ins needs a username to moderate them")).WithField("Username")
+	}
+
+	writeIns := WriteInSettings{Allowed: req.AllowWriteIns}
+	if req.AllowWriteIns {
+		writeIns.Limit = req.WriteInLimit
+		if writeIns.Limit == 0 {
+			writeIns.Limit = AppConfig.Limits.DefaultWriteInLimit
+		}
+	}
+
+	if req.InvitationCount > AppConfig.Limits.MaxInvitationCount {
+		return response.NewError(http.StatusBadRequest, response.CodeInvalidSettings, "Too many invitations!", fmt.Errorf("can't generate more than %d invitations", AppConfig.Limits.MaxInvitationCount)).WithField("InvitationCount")
+	}
+
+	eligibility := Eligibility{
+		AllowedVoters:   req.AllowedVoters,
+		InvitationCodes: make(map[string]bool, req.InvitationCount),
+	}
+	invitationCodes := make([]string, 0, req.InvitationCount)
+	for range req.InvitationCount {
+		code := rand.Text()
+		eligibility.InvitationCodes[code] = false
+		invitationCodes = append(invitationCodes, code)
+	}
+
+	if req.QuorumPercentage > 100 {
+		return response.NewError(http.StatusBadRequest, response.CodeInvalidSettings, "Invalid quorum!", errors.New("the quorum percentage can't be greater than 100")).WithField("QuorumPercentage")
+	}
+
+	if req.QuorumPercentage > 0 && !eligibility.IsRestricted() {
+		return response.NewError(http.StatusBadRequest, response.CodeInvalidSettings, "Invalid quorum!", errors.New("a quorum percentage needs allowed voters or invitations")).WithField("QuorumPercentage")
 	}
 
 	id := uuid.New()
 	key := id.String()
 	room := Room[time.Time]{
-		Id:         id,
-		Title:      req.Title,
-		Options:    req.PollOptions,
-		Votes:      make(map[string]Vote),
-		ValidUntil: time.Now().Add(req.PollingDuration),
+		Id:          id,
+		Title:       req.Title,
+		Owner:       req.Username,
+		Options:     options,
+		WriteIns:    writeIns,
+		Eligibility: eligibility,
+		Quorum: Quorum{
+			MinBallots:    req.QuorumBallots,
+			MinPercentage: req.QuorumPercentage,
+		},
+		SecretBallot: req.SecretBallot,
+		Votes:        make(map[string]Vote),
+		ValidUntil:   time.Now().Add(req.PollingDuration),
 	}
-	log.Printf("Storing new poll with id: %s\n", id)
+	response.Logger(r.Context()).Info("Storing new poll", "poll_id", id, "username", req.Username)
 
 	GlobalState.Lock.Lock()
 	defer GlobalState.Lock.Unlock()
 	GlobalState.Rooms[key] = room
 
-	_ = response.NewResponseBuilder(http.StatusOK).
-		SetBody(CreatePollResponse{Msg: "Success!", PollId: id}).
-		SendAsJSON(w)
+	return response.NewResponseBuilder(http.StatusOK).
+		SetBody(CreatePollResponse{Msg: "Success!", PollId: id, InvitationCodes: invitationCodes}).
+		Send(w, r)
 }
 
-func GetPollInfo(w http.ResponseWriter, r *http.Request) {
-	now := time.Now()
-
+func GetPollInfo(w http.ResponseWriter, r *http.Request) error {
 	pollStrId := r.PathValue("pollId")
 	if pollStrId == "" {
-		_ = response.NewResponseBuilder(http.StatusNotFound).
-			SetError("Poll not found!", errors.New("no pollId supplied")).
-			SendAsJSON(w)
-		return
+		return response.NewError(http.StatusNotFound, response.CodePollNotFound, "Poll not found!", errors.New("no pollId supplied"))
 	}
-	log.Printf("The poll id from the path is: %s", pollStrId)
 
 	pollId, err := uuid.Parse(pollStrId)
 	if err != nil {
-		_ = response.NewResponseBuilder(http.StatusNotFound).
-			SetError("Poll not found!", err).
-			SendAsJSON(w)
-		return
+		return response.NewError(http.StatusNotFound, response.CodePollNotFound, "Poll not found!", err)
 	}
 
-	GlobalState.Lock.RLock()
-	pollInfo, found := GlobalState.Rooms[pollId.String()]
-	GlobalState.Lock.RUnlock()
-	log.Printf("Room already exists? %t", found)
+	pollInfo, found := lookupPoll(r.Context(), pollId)
+	response.Logger(r.Context()).Debug("Looked up poll", "poll_id", pollId, "found", found)
 
 	if !found {
-		_ = response.NewResponseBuilder(http.StatusNotFound).
-			SetError("Poll not found!", errors.New("the poll was not found")).
-			SendAsJSON(w)
-		return
+		return response.NewError(http.StatusNotFound, response.CodePollNotFound, "Poll not found!", errors.New("the poll was not found"))
 	}
 
-	shouldComputeSummary := now.After(pollInfo.ValidUntil) && pollInfo.Summary == nil
-	if shouldComputeSummary {
-		computeSummary(&pollInfo)
+	posixInfo := toPosixTime(pollInfo)
+
+	return response.NewResponseBuilder(http.StatusOK).
+		SetBody(posixInfo).
+		Send(w, r)
+}
+
+// lookupPoll returns the poll, computing its summary the first time it's looked up after it closed.
+func lookupPoll(ctx context.Context, pollId uuid.UUID) (Room[time.Time], bool) {
+	now := time.Now()
+
+	GlobalState.Lock.RLock()
+	pollInfo, found := GlobalState.Rooms[pollId.String()]
+	GlobalState.Lock.RUnlock()
+
+	shouldComputeSummary := found && now.After(pollInfo.ValidUntil) && pollInfo.Summary == nil
+	if !shouldComputeSummary {
+		return pollInfo, found
 	}
 
 	GlobalState.Lock.Lock()
-	GlobalState.Rooms[pollId.String()] = pollInfo
-	GlobalState.Lock.Unlock()
-
-	posixInfo := toPosixTime(pollInfo)
+	defer GlobalState.Lock.Unlock()
 
-	_ = response.NewResponseBuilder(http.StatusOK).
-		SetBody(posixInfo).
-		SendAsJSON(w)
+	// Another request may have computed it, or deleted the poll, while unlocked.
+	pollInfo, found = GlobalState.Rooms[pollId.String()]
+	if found && pollInfo.Summary == nil {
+		computeSummary(ctx, &pollInfo)
+		GlobalState.Rooms[pollId.String()] = pollInfo
+	}
+	return pollInfo, found
 }
 
+// toPosixTime also works as the public view of the room,
+// so invitation codes are never included and secret ballots are anonymized.
 func toPosixTime(r Room[time.Time]) Room[int64] {
 	posixTime := r.ValidUntil.UnixMilli()
+
+	votes := r.Votes
+	if r.SecretBallot {
+		votes = make(map[string]Vote, len(r.Votes))
+		for i, vote := range r.Ballots() {
+			votes[fmt.Sprintf("ballot-%d", i+1)] = vote
+		}
+	}
+
 	return Room[int64]{
-		Id:         r.Id,
-		Title:      r.Title,
-		Options:    r.Options,
-		Votes:      r.Votes,
-		Summary:    r.Summary,
-		ValidUntil: posixTime,
+		Id:             r.Id,
+		Title:          r.Title,
+		Owner:          r.Owner,
+		Options:        r.Options,
+		WriteIns:       r.WriteIns,
+		PendingOptions: r.PendingOptions,
+		Eligibility:    Eligibility{AllowedVoters: r.Eligibility.AllowedVoters},
+		Quorum:         r.Quorum,
+		SecretBallot:   r.SecretBallot,
+		Votes:          votes,
+		Summary:        r.Summary,
+		ValidUntil:     posixTime,
 	}
 }
 
-func computeSummary(room *Room[time.Time]) {
-	summary := &PollSummary{
-		Rounds: make([]map[string]uint, 0),
+// rankingByIds returns the ranking of the request keyed by option ids,
+// translating the deprecated label keyed Options if needed.
+func rankingByIds(req VoteInPollRequest, room Room[time.Time]) (map[uuid.UUID]uint, error) {
+	if len(req.Ranking) > 0 || len(req.Options) == 0 {
+		return req.Ranking, nil
 	}
 
-	for roundIdx := range len(room.Options) {
-		round := uint(roundIdx + 1)
-		roundTally := make(map[string]uint)
-
-		for _, vote := range room.Votes {
-			for _, r := range vote.Ranking {
-				if r.Position > round {
-					continue
-				}
-
-				roundTally[r.Option] += 1
-			}
-		}
-
-		summary.Rounds = append(summary.Rounds, roundTally)
-
-		maxOpt := "INVALID"
-		var maxCount uint = 0
-		var totalCount uint = 0
-		isUnique := true
-		for opt, voteCount := range roundTally {
-			totalCount += voteCount
-
-			if voteCount > maxCount {
-				maxOpt = opt
-				maxCount = voteCount
-				isUnique = true
-			} else if voteCount == maxCount {
-				isUnique = false
-			}
-		}
-
-		moreThanFraction := maxCount > (totalCount / uint(len(room.Options)))
-		log.Printf("Round: %d - IsUnique: %t\n", round, isUnique)
-		log.Printf("Computing summary: %d > (%d / %d)\n", maxCount, totalCount, len(room.Options))
-		if moreThanFraction && isUnique || round == uint(len(room.Options)) {
-			log.Printf("Winner: %s", maxOpt)
-			summary.Winner = maxOpt
-			summary.WinnerVoteCount = maxCount
-			summary.TotalVoteCount = totalCount
-			break
+	ranking := make(map[uuid.UUID]uint, len(req.Options))
+	for label, position := range req.Options {
+		opt, found := room.FindOptionByLabel(label)
+		if !found {
+			return nil, fmt.Errorf("no option %s exists in the poll", label)
 		}
+		ranking[opt.Id] = position
 	}
 
-	room.Summary = summary
+	return ranking, nil
 }
 
-type VoteInPollRequest struct {
-	Username string
-	PollId   uuid.UUID
-	Options  map[string]uint
+// rankingField is the path of the request field that ranks the option.
+func rankingField(req VoteInPollRequest, opt Option) string {
+	if len(req.Ranking) > 0 || len(req.Options) == 0 {
+		return "Ranking." + opt.Id.String()
+	}
+	return "Options." + opt.Label
 }
 
-func VoteInPoll(w http.ResponseWriter, r *http.Request) {
+func VoteInPoll(w http.ResponseWriter, r *http.Request) error {
 	now := time.Now()
+	// Logging the ballot would tell who voted for what.
+	OmitRequestBody(r)
 
 	var req VoteInPollRequest
-	err := json.NewDecoder(r.Body).Decode(&req)
-	if err != nil {
-		_ = response.NewResponseBuilder(http.StatusBadRequest).
-			SetError("Invalid body!", err).
-			SendAsJSON(w)
-		return
+	if err := response.DecodeJSON(r, &req); err != nil {
+		return err
 	}
 
-	GlobalState.Lock.RLock()
+	// The versioned route takes the poll from the path, the body may omit it.
+	if pathId := r.PathValue("pollId"); pathId != "" {
+		pollId, err := uuid.Parse(pathId)
+		if err != nil {
+			return response.NewError(http.StatusNotFound, response.CodePollNotFound, "Poll not found!", err)
+		}
+
+		if req.PollId != uuid.Nil && req.PollId != pollId {
+			return response.NewError(http.StatusBadRequest, response.CodeInvalidBody, "Mismatched poll!", errors.New("the poll of the body is not the one of the path")).WithField("PollId")
+		}
+		req.PollId = pollId
+	}
+
+	if err := limitByUsername(w, r, req.Username); err != nil {
+		return err
+	}
+
+	GlobalState.Lock.Lock()
+	defer GlobalState.Lock.Unlock()
+
 	roomInfo, found := GlobalState.Rooms[req.PollId.String()]
-	GlobalState.Lock.RUnlock()
 	if !found {
-		_ = response.NewResponseBuilder(http.StatusNotFound).
-			SetError("The room was not found!", err).
-			SendAsJSON(w)
-		return
+		return response.NewError(http.StatusNotFound, response.CodePollNotFound, "The room was not found!", errors.New("the poll was not found"))
 	}
 
 	if _, found := roomInfo.Votes[req.Username]; found {
-		_ = response.NewResponseBuilder(http.StatusBadRequest).
-			SetError("The user has already voted!", errors.New("a user can't vote twice")).
-			SendAsJSON(w)
-		return
+		return response.NewError(http.StatusBadRequest, response.CodeAlreadyVoted, "The user has already voted!", errors.New("a user can't vote twice"))
 	}
 
 	if now.After(roomInfo.ValidUntil) {
-		_ = response.NewResponseBuilder(http.StatusBadRequest).
-			SetError("The poll already ended!", errors.New("the poll has ended")).
-			SendAsJSON(w)
-		return
+		return response.NewError(http.StatusBadRequest, response.CodePollClosed, "The poll already ended!", errors.New("the poll has ended"))
+	}
+
+	// Anyone can send a username, allowed voters must authenticate as it.
+	username, err := authenticate(w, r)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(roomInfo.Eligibility.AllowedVoters, req.Username) && username != req.Username {
+		return unauthorized(w, fmt.Errorf("the allowed voter %s must authenticate", req.Username))
+	}
+
+	redeemCode, err := roomInfo.Eligibility.CanVote(req.Username, req.InvitationCode)
+	if err != nil {
+		return response.NewError(http.StatusForbidden, response.CodeNotEligible, "The user can't vote in this poll!", err)
+	}
+
+	reqRanking, err := rankingByIds(req, roomInfo)
+	if err != nil {
+		return response.NewError(http.StatusBadRequest, response.CodeUnknownOption, "Unknown voting option!", err).WithField("Options")
+	}
+
+	for optId := range reqRanking {
+		if _, found := roomInfo.FindOption(optId); !found {
+			return response.NewError(http.StatusBadRequest, response.CodeUnknownOption, "Unknown voting option!", fmt.Errorf("no option with id %s exists in the poll", optId)).WithField("Ranking." + optId.String())
+		}
 	}
 
 	ranking := make([]Rank, 0, len(roomInfo.Options))
 	for _, opt := range roomInfo.Options {
-		position, found := req.Options[opt]
+		position, found := reqRanking[opt.Id]
+		if !found && opt.IsWriteIn() {
+			// Write-ins may be left unranked.
+			continue
+		}
+
 		if !found {
-			_ = response.NewResponseBuilder(http.StatusBadRequest).
-				SetError("Incomplete voting options!", fmt.Errorf("no option %s found", opt)).
-				SendAsJSON(w)
-			return
+			return response.NewError(http.StatusBadRequest, response.CodeIncompleteRanking, "Incomplete voting options!", fmt.Errorf("no option %s found", opt.Label)).WithField(rankingField(req, opt))
 		}
 
 		if position == 0 {
-			_ = response.NewResponseBuilder(http.StatusBadRequest).
-				SetError("The 0 rank is not existent!", fmt.Errorf("option %s has 0 rank", opt)).
-				SendAsJSON(w)
-			return
+			return response.NewError(http.StatusBadRequest, response.CodeInvalidRank, "The 0 rank is not existent!", fmt.Errorf("option %s has 0 rank", opt.Label)).WithField(rankingField(req, opt))
 		}
 
 		if position > uint(len(roomInfo.Options)) {
-			_ = response.NewResponseBuilder(http.StatusBadRequest).
-				SetError("An option has a rank greater than voting options!", fmt.Errorf("option %s has a big rank", opt)).
-				SendAsJSON(w)
-			return
+			return response.NewError(http.StatusBadRequest, response.CodeInvalidRank, "An option has a rank greater than voting options!", fmt.Errorf("option %s has a big rank", opt.Label)).WithField(rankingField(req, opt))
 		}
 
 		ranking = append(ranking, Rank{
-			Option:   opt,
-			Position: req.Options[opt],
+			OptionId: opt.Id,
+			Position: position,
 		})
 	}
 
-	roomInfo.Votes[req.Username] = Vote{
+	voteKey := req.Username
+	if redeemCode {
+		roomInfo.Eligibility.InvitationCodes[req.InvitationCode] = true
+		if voteKey == "" {
+			voteKey = "anonymous-" + uuid.NewString()
+		}
+	}
+
+	roomInfo.Votes[voteKey] = Vote{
 		Username: req.Username,
 		Ranking:  ranking,
 	}
 
-	GlobalState.Lock.Lock()
 	GlobalState.Rooms[req.PollId.String()] = roomInfo
-	GlobalState.Lock.Unlock()
+	PollEvents.Publish(req.PollId.String())
+	votesCast.Inc()
+
+	return response.NewResponseBuilder(http.StatusOK).
+		Send(w, r)
+}
+
+func AddWriteIn(w http.ResponseWriter, r *http.Request) error {
+	now := time.Now()
+
+	pollId, err := uuid.Parse(r.PathValue("pollId"))
+	if err != nil {
+		return response.NewError(http.StatusNotFound, response.CodePollNotFound, "Poll not found!", err)
+	}
+
+	var req AddWriteInRequest
+	if err := response.DecodeJSON(r, &req); err != nil {
+		return err
+	}
+
+	if req.Username == "" || req.Label == "" {
+		return response.NewError(http.StatusBadRequest, response.CodeInvalidOption, "Invalid write-in!", errors.New("a write-in needs a username and a label")).WithField("Label")
+	}
+
+	GlobalState.Lock.Lock()
+	defer GlobalState.Lock.Unlock()
+
+	roomInfo, found := GlobalState.Rooms[pollId.String()]
+	if !found {
+		return response.NewError(http.StatusNotFound, response.CodePollNotFound, "Poll not found!", errors.New("the poll was not found"))
+	}
+
+	if now.After(roomInfo.ValidUntil) {
+		return response.NewError(http.StatusBadRequest, response.CodePollClosed, "The poll already ended!", errors.New("the poll has ended"))
+	}
+
+	// The write-ins of the owner skip the moderation.
+	username, err := authenticate(w, r)
+	if err != nil {
+		return err
+	}
+
+	if !roomInfo.WriteIns.Allowed {
+		return response.NewError(http.StatusForbidden, response.CodeWriteInsDisabled, "Write-ins are not allowed!", errors.New("the poll doesn't accept write-ins"))
+	}
+
+	if roomInfo.WriteInCount() >= roomInfo.WriteIns.Limit {
+		return response.NewError(http.StatusBadRequest, response.CodeWriteInLimit, "Too many write-ins!", fmt.Errorf("the poll only accepts %d write-ins", roomInfo.WriteIns.Limit))
+	}
+
+	_, alreadyExists := roomInfo.FindOptionByLabel(req.Label)
+	for _, opt := range roomInfo.PendingOptions {
+		alreadyExists = alreadyExists || opt.Label == req.Label
+	}
+	if alreadyExists {
+		return response.NewError(http.StatusBadRequest, response.CodeInvalidOption, "Invalid write-in!", fmt.Errorf("option %s already exists", req.Label)).WithField("Label")
+	}
+
+	opt := Option{
+		Id:          uuid.New(),
+		Label:       req.Label,
+		Description: req.Description,
+		Link:        req.Link,
+		AddedBy:     req.Username,
+	}
+	msg := "Write-in waiting for approval!"
+	if username != "" && username == roomInfo.Owner {
+		roomInfo.Options = append(roomInfo.Options, opt)
+		msg = "Write-in added!"
+	} else {
+		roomInfo.PendingOptions = append(roomInfo.PendingOptions, opt)
+	}
+	GlobalState.Rooms[pollId.String()] = roomInfo
+	PollEvents.Publish(pollId.String())
+	response.Logger(r.Context()).Info("Write-in added", "poll_id", pollId, "option_id", opt.Id, "username", req.Username)
+
+	return response.NewResponseBuilder(http.StatusOK).
+		SetBody(AddWriteInResponse{OptionId: opt.Id, Msg: msg}).
+		Send(w, r)
+}
+
+func ModerateWriteIn(w http.ResponseWriter, r *http.Request) error {
+	now := time.Now()
+
+	pollId, err := uuid.Parse(r.PathValue("pollId"))
+	if err != nil {
+		return response.NewError(http.StatusNotFound, response.CodePollNotFound, "Poll not found!", err)
+	}
+
+	optionId, err := uuid.Parse(r.PathValue("optionId"))
+	if err != nil {
+		return response.NewError(http.StatusNotFound, response.CodeOptionNotFound, "Write-in not found!", err)
+	}
+
+	var req ModerateWriteInRequest
+	if err := response.DecodeJSON(r, &req); err != nil {
+		return err
+	}
+
+	GlobalState.Lock.Lock()
+	defer GlobalState.Lock.Unlock()
+
+	roomInfo, found := GlobalState.Rooms[pollId.String()]
+	if !found {
+		return response.NewError(http.StatusNotFound, response.CodePollNotFound, "Poll not found!", errors.New("the poll was not found"))
+	}
+
+	if err := checkOwner(w, r, roomInfo, "Only the owner can moderate write-ins!"); err != nil {
+		return err
+	}
+
+	if now.After(roomInfo.ValidUntil) {
+		return response.NewError(http.StatusBadRequest, response.CodePollClosed, "The poll already ended!", errors.New("the poll has ended"))
+	}
+
+	pendingIdx := -1
+	for i, opt := range roomInfo.PendingOptions {
+		if opt.Id == optionId {
+			pendingIdx = i
+		}
+	}
+	if pendingIdx == -1 {
+		return response.NewError(http.StatusNotFound, response.CodeOptionNotFound, "Write-in not found!", errors.New("no pending write-in with that id"))
+	}
+
+	opt := roomInfo.PendingOptions[pendingIdx]
+	roomInfo.PendingOptions = append(roomInfo.PendingOptions[:pendingIdx:pendingIdx], roomInfo.PendingOptions[pendingIdx+1:]...)
+	if req.Approve {
+		roomInfo.Options = append(roomInfo.Options, opt)
+	}
+	GlobalState.Rooms[pollId.String()] = roomInfo
+	PollEvents.Publish(pollId.String())
+	response.Logger(r.Context()).Info("Write-in moderated", "poll_id", pollId, "option_id", opt.Id, "username", roomInfo.Owner, "approved", req.Approve)
+
+	return response.NewResponseBuilder(http.StatusOK).
+		Send(w, r)
+}
+
+// isUser checks the credentials of a user, GlobalState must be locked.
+func isUser(username string, password string) bool {
+	storedPassword, found := GlobalState.Users[username]
+	return found && storedPassword == password && username != ""
+}
+
+// checkPassword checks the password of an existing user, counting the failures since its last success
+// to lock the account once there are too many. The error is only for locked accounts.
+// GlobalState must be locked.
+func checkPassword(w http.ResponseWriter, r *http.Request, username string, password string) (bool, error) {
+	now := time.Now()
+
+	failures := GlobalState.LoginFailures[username]
+	if now.Before(failures.LockedUntil) {
+		setRetryAfter(w, failures.LockedUntil.Sub(now))
+		return false, response.NewError(http.StatusTooManyRequests, response.CodeAccountLocked, "Account locked!", errors.New("too many failed logins, try again later"))
+	}
+
+	if isUser(username, password) {
+		delete(GlobalState.LoginFailures, username)
+		return true, nil
+	}
+
+	loginFailures.Inc()
+	failures.Count++
+	if failures.Count >= AppConfig.RateLimit.MaxLoginFailures {
+		failures = LoginFailures{LockedUntil: now.Add(time.Duration(AppConfig.RateLimit.LockoutDuration))}
+		response.Logger(r.Context()).Warn("Account locked", "username", username, "until", failures.LockedUntil)
+	}
+	GlobalState.LoginFailures[username] = failures
+	return false, nil
+}
+
+// authenticate returns the user whose HTTP Basic credentials the request has,
+// an empty username if it has none. GlobalState must be locked.
+func authenticate(w http.ResponseWriter, r *http.Request) (string, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return "", nil
+	}
+
+	if err := limitByUsername(w, r, username); err != nil {
+		return "", err
+	}
+
+	// Unknown users have no account to lock.
+	if _, found := GlobalState.Users[username]; !found {
+		return "", unauthorized(w, errors.New("password/username don't match"))
+	}
+
+	ok, err := checkPassword(w, r, username, password)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", unauthorized(w, errors.New("password/username don't match"))
+	}
+	return username, nil
+}
+
+// checkOwner checks the request is authenticated as the owner of the room, GlobalState must be locked.
+func checkOwner(w http.ResponseWriter, r *http.Request, room Room[time.Time], msg string) error {
+	username, err := authenticate(w, r)
+	if err != nil {
+		return err
+	}
+
+	if username == "" {
+		return unauthorized(w, errors.New("the request has no credentials"))
+	}
+	if username != room.Owner {
+		return response.NewError(http.StatusForbidden, response.CodeNotOwner, msg, errors.New("the user doesn't own the poll"))
+	}
+	return nil
+}
+
+// unauthorized asks the client to send its credentials with HTTP Basic authentication.
+func unauthorized(w http.ResponseWriter, reason error) error {
+	w.Header().Set("WWW-Authenticate", `Basic realm="RankPoll"`)
+	return response.NewError(http.StatusUnauthorized, response.CodeInvalidCredentials, "Invalid credentials!", reason)
+}
+
+func UpdatePoll(w http.ResponseWriter, r *http.Request) error {
+	now := time.Now()
+
+	pollId, err := uuid.Parse(r.PathValue("pollId"))
+	if err != nil {
+		return response.NewError(http.StatusNotFound, response.CodePollNotFound, "Poll not found!", err)
+	}
+
+	var req UpdatePollRequest
+	if err := response.DecodeJSON(r, &req); err != nil {
+		return err
+	}
+
+	GlobalState.Lock.Lock()
+	defer GlobalState.Lock.Unlock()
+
+	roomInfo, found := GlobalState.Rooms[pollId.String()]
+	if !found {
+		return response.NewError(http.StatusNotFound, response.CodePollNotFound, "Poll not found!", errors.New("the poll was not found"))
+	}
+
+	if err := checkOwner(w, r, roomInfo, "Only the owner can update the poll!"); err != nil {
+		return err
+	}
+
+	if now.After(roomInfo.ValidUntil) {
+		return response.NewError(http.StatusBadRequest, response.CodePollClosed, "The poll already ended!", errors.New("the poll has ended"))
+	}
+
+	if req.Title != nil {
+		roomInfo.Title = *req.Title
+	}
+
+	if req.ValidUntil != nil {
+		roomInfo.ValidUntil = time.UnixMilli(*req.ValidUntil)
+		if roomInfo.ValidUntil.Before(now) {
+			roomInfo.ValidUntil = now
+		}
+	}
+
+	GlobalState.Rooms[pollId.String()] = roomInfo
+	PollEvents.Publish(pollId.String())
+	response.Logger(r.Context()).Info("Poll updated", "poll_id", pollId, "username", roomInfo.Owner)
+
+	return response.NewResponseBuilder(http.StatusOK).
+		SetBody(toPosixTime(roomInfo)).
+		Send(w, r)
+}
+
+func DeletePoll(w http.ResponseWriter, r *http.Request) error {
+	pollId, err := uuid.Parse(r.PathValue("pollId"))
+	if err != nil {
+		return response.NewError(http.StatusNotFound, response.CodePollNotFound, "Poll not found!", err)
+	}
+
+	GlobalState.Lock.Lock()
+	defer GlobalState.Lock.Unlock()
+
+	roomInfo, found := GlobalState.Rooms[pollId.String()]
+	if !found {
+		return response.NewError(http.StatusNotFound, response.CodePollNotFound, "Poll not found!", errors.New("the poll was not found"))
+	}
+
+	if err := checkOwner(w, r, roomInfo, "Only the owner can delete the poll!"); err != nil {
+		return err
+	}
+
+	delete(GlobalState.Rooms, pollId.String())
+	PollEvents.Publish(pollId.String())
+	response.Logger(r.Context()).Info("Poll deleted", "poll_id", pollId, "username", roomInfo.Owner)
 
-	_ = response.NewResponseBuilder(http.StatusOK).
-		SendAsJSON(w)
+	return response.NewResponseBuilder(http.StatusOK).
+		Send(w, r)
 }
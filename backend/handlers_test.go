@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ElrohirGT/RankPoll/response"
 	"github.com/google/uuid"
 )
 
@@ -31,7 +32,7 @@ func emulateHttp[T any](method string, body T, handler http.Handler) (*http.Resp
 }
 
 func createOrLoginUser(req CreateOrLoginUserRequest) (*http.Response, error) {
-	return emulateHttp(string(http.MethodPost), req, http.HandlerFunc(CreateOrLoginUser))
+	return emulateHttp(string(http.MethodPost), req, response.Handle(CreateOrLoginUser))
 }
 
 func TestCreateOrLoginUser(t *testing.T) {
@@ -82,6 +83,36 @@ func TestCreateOrLoginUser(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "Lock account after failed logins",
+			doReq: func(t *testing.T) {
+				_, err := createOrLoginUser(CreateOrLoginUserRequest{Username: "fagd", Password: "12345"})
+				if err != nil {
+					t.Fatalf("Failed to make request: %s\n", err)
+				}
+
+				for range AppConfig.RateLimit.MaxLoginFailures {
+					resp, err := createOrLoginUser(CreateOrLoginUserRequest{Username: "fagd", Password: "wrong"})
+					if err != nil {
+						t.Fatalf("Failed to make request: %s\n", err)
+					}
+
+					if resp.StatusCode != http.StatusBadRequest {
+						t.Fatalf("Expected invalid credentials but got %d\n", resp.StatusCode)
+					}
+				}
+
+				resp, err := createOrLoginUser(CreateOrLoginUserRequest{Username: "fagd", Password: "12345"})
+				if err != nil {
+					t.Fatalf("Failed to make request: %s\n", err)
+				}
+
+				if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+					bodyStr, _ := io.ReadAll(resp.Body)
+					t.Fatalf("Expected the account to be locked but got (%d): %s\n", resp.StatusCode, bodyStr)
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -93,7 +124,7 @@ func TestCreateOrLoginUser(t *testing.T) {
 }
 
 func createPoll(req CreatePollRequest) (*http.Response, error) {
-	return emulateHttp(string(http.MethodPost), req, http.HandlerFunc(CreatePoll))
+	return emulateHttp(string(http.MethodPost), req, response.Handle(CreatePoll))
 }
 
 func TestCreatePoll(t *testing.T) {
@@ -141,7 +172,7 @@ func getPollInfo(pollId uuid.UUID) (*http.Response, error) {
 	httpReq.SetPathValue("pollId", pollId.String())
 
 	w := httptest.NewRecorder()
-	GetPollInfo(w, httpReq)
+	response.Handle(GetPollInfo).ServeHTTP(w, httpReq)
 	return w.Result(), nil
 }
 
@@ -178,7 +209,7 @@ func TestGetPollInfo(t *testing.T) {
 					t.Fatalf("The request failed somehow (%d)! %s\n", resp.StatusCode, bodyStr)
 				}
 
-				var roomInfo Room
+				var roomInfo Room[int64]
 				err = json.NewDecoder(resp.Body).Decode(&roomInfo)
 				if err != nil {
 					t.Fatalf("Failed to decode body: %s\n", err)
@@ -193,6 +224,41 @@ func TestGetPollInfo(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "Poll keeps its settings",
+			doReq: func(t *testing.T) {
+				resp, err := createPoll(CreatePollRequest{
+					Title:           "Favorite Profesion?",
+					PollOptions:     []string{"Teacher", "Doctor", "Plumber"},
+					PollingDuration: 5 * time.Second,
+					SecretBallot:    true,
+				})
+				if err != nil {
+					t.Fatalf("Failed to make make request: %s\n", err)
+				}
+
+				var createPollResponse CreatePollResponse
+				err = json.NewDecoder(resp.Body).Decode(&createPollResponse)
+				if err != nil {
+					t.Fatalf("Failed to parse body: %s\n", err)
+				}
+
+				resp, err = getPollInfo(createPollResponse.PollId)
+				if err != nil {
+					t.Fatalf("Failed to make request: %s\n", err)
+				}
+
+				var roomInfo Room[int64]
+				err = json.NewDecoder(resp.Body).Decode(&roomInfo)
+				if err != nil {
+					t.Fatalf("Failed to decode body: %s\n", err)
+				}
+
+				if !roomInfo.SecretBallot {
+					t.Fatalf("The poll should have a secret ballot!\n")
+				}
+			},
+		},
 		{
 			name: "Create and get poll info after voting",
 			doReq: func(t *testing.T) {
@@ -289,7 +355,7 @@ func TestGetPollInfo(t *testing.T) {
 					t.Fatalf("Failed to get poll info: %s\n", err)
 				}
 
-				var respBody Room
+				var respBody Room[int64]
 				err = json.NewDecoder(resp.Body).Decode(&respBody)
 				if err != nil {
 					t.Fatalf("Can't parse body of poll info: %s\n", err)
@@ -304,6 +370,58 @@ func TestGetPollInfo(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "Poll without quorum has no winner",
+			doReq: func(t *testing.T) {
+				resp, err := createPoll(CreatePollRequest{
+					Title:           "Favorite Profesion?",
+					PollOptions:     []string{"Teacher", "Doctor"},
+					PollingDuration: 100 * time.Millisecond,
+					QuorumBallots:   2,
+				})
+				if err != nil {
+					t.Fatalf("Failed to make request: %s\n", err)
+				}
+
+				var createPollResponse CreatePollResponse
+				err = json.NewDecoder(resp.Body).Decode(&createPollResponse)
+				if err != nil {
+					t.Fatalf("Failed to parse body: %s\n", err)
+				}
+
+				resp, err = voteInPoll(VoteInPollRequest{Username: "Tyron", PollId: createPollResponse.PollId, Options: map[string]uint{
+					"Teacher": 2,
+					"Doctor":  1,
+				}})
+				if err != nil {
+					t.Fatalf("Failed to vote in poll: %s\n", err)
+				}
+				if resp.StatusCode != http.StatusOK {
+					bodyStr, _ := io.ReadAll(resp.Body)
+					t.Fatalf("Failed to vote in poll by some reason (%d): %s\n", resp.StatusCode, bodyStr)
+				}
+
+				time.Sleep(100 * time.Millisecond)
+				resp, err = getPollInfo(createPollResponse.PollId)
+				if err != nil {
+					t.Fatalf("Failed to get poll info: %s\n", err)
+				}
+
+				var respBody Room[int64]
+				err = json.NewDecoder(resp.Body).Decode(&respBody)
+				if err != nil {
+					t.Fatalf("Can't parse body of poll info: %s\n", err)
+				}
+
+				if respBody.Summary == nil {
+					t.Fatalf("Polling hasn't ended but it should have!\n")
+				}
+
+				if respBody.Summary.Status != StatusNoQuorum || respBody.Summary.Winner != "" {
+					t.Fatalf("A winner was named without quorum!\n%#v", *respBody.Summary)
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -315,7 +433,37 @@ func TestGetPollInfo(t *testing.T) {
 }
 
 func voteInPoll(req VoteInPollRequest) (*http.Response, error) {
-	return emulateHttp(http.MethodPost, req, http.HandlerFunc(VoteInPoll))
+	return emulateHttp(http.MethodPost, req, response.Handle(VoteInPoll))
+}
+
+// credentials are sent with HTTP Basic authentication, unless the username is empty.
+type credentials struct {
+	username string
+	password string
+}
+
+func (c credentials) setOn(r *http.Request) {
+	if c.username != "" {
+		r.SetBasicAuth(c.username, c.password)
+	}
+}
+
+func voteInPollAs(req VoteInPollRequest, creds credentials) (*http.Response, error) {
+	var reqBody bytes.Buffer
+	err := json.NewEncoder(&reqBody).Encode(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, "/", &reqBody)
+	if err != nil {
+		return nil, err
+	}
+	creds.setOn(httpReq)
+
+	w := httptest.NewRecorder()
+	response.Handle(VoteInPoll).ServeHTTP(w, httpReq)
+	return w.Result(), nil
 }
 
 func TestVoteInPoll(t *testing.T) {
@@ -419,9 +567,449 @@ func TestVoteInPoll(t *testing.T) {
 					bodyStr, _ := io.ReadAll(resp.Body)
 					t.Fatalf("Poll voting should have failed but it didn't (%d): %s\n", resp.StatusCode, &bodyStr)
 				}
+
+				var errResponse response.ErrorResponse
+				err = json.NewDecoder(resp.Body).Decode(&errResponse)
+				if err != nil {
+					t.Fatalf("Failed to decode error: %s\n", err)
+				}
+
+				if errResponse.Code != response.CodePollClosed {
+					t.Fatalf("Expected %s code but got %s\n", response.CodePollClosed, errResponse.Code)
+				}
+			},
+		},
+		{
+			name: "Vote in non existent poll",
+			doReq: func(t *testing.T) {
+				resp, err := voteInPoll(VoteInPollRequest{
+					Username: "FAGD",
+					PollId:   uuid.New(),
+					Options:  map[string]uint{"Español": 1},
+				})
+				if err != nil {
+					t.Fatalf("Failed to make request: %s\n", err)
+				}
+
+				if resp.StatusCode != http.StatusNotFound {
+					bodyStr, _ := io.ReadAll(resp.Body)
+					t.Fatalf("Expected 404 but got (%d): %s\n", resp.StatusCode, bodyStr)
+				}
+			},
+		},
+		{
+			name: "Vote using option ids",
+			doReq: func(t *testing.T) {
+				resp, err := createPoll(CreatePollRequest{
+					Title:           "Lenguaje",
+					PollingDuration: 5 * time.Second,
+					Options: []PollOptionRequest{
+						{Label: "Español", Description: "Spoken in Guatemala"},
+						{Label: "Alemán", Link: "https://de.wikipedia.org"},
+					},
+				})
+				if err != nil {
+					t.Fatalf("Failed to create poll: %s\n", err)
+				}
+
+				var pollResponse CreatePollResponse
+				err = json.NewDecoder(resp.Body).Decode(&pollResponse)
+				if err != nil {
+					t.Fatalf("Failed to decode response: %s\n", err)
+				}
+
+				resp, err = getPollInfo(pollResponse.PollId)
+				if err != nil {
+					t.Fatalf("Failed to get poll info: %s\n", err)
+				}
+
+				var roomInfo Room[int64]
+				err = json.NewDecoder(resp.Body).Decode(&roomInfo)
+				if err != nil {
+					t.Fatalf("Failed to decode body: %s\n", err)
+				}
+
+				if roomInfo.Options[0].Description != "Spoken in Guatemala" {
+					t.Fatalf("Option description wasn't stored! %#v\n", roomInfo.Options[0])
+				}
+
+				resp, err = voteInPoll(VoteInPollRequest{
+					Username: "FAGD",
+					PollId:   pollResponse.PollId,
+					Ranking: map[uuid.UUID]uint{
+						roomInfo.Options[0].Id: 2,
+						roomInfo.Options[1].Id: 1,
+					},
+				})
+				if err != nil {
+					t.Fatalf("Failed to make request: %s\n", err)
+				}
+
+				if resp.StatusCode != http.StatusOK {
+					bodyStr, _ := io.ReadAll(resp.Body)
+					t.Fatalf("Poll voting failed somehow (%d): %s\n", resp.StatusCode, bodyStr)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			CleanGlobalState()
+			tt.doReq(t)
+		})
+	}
+}
+
+func TestVoterEligibility(t *testing.T) {
+	tests := []struct {
+		name  string
+		doReq func(t *testing.T)
+	}{
+		{
+			name: "Only allowed voters and invitations can vote",
+			doReq: func(t *testing.T) {
+				_, err := createOrLoginUser(CreateOrLoginUserRequest{Username: "Tyron", Password: "12345"})
+				if err != nil {
+					t.Fatalf("Failed to create user: %s\n", err)
+				}
+
+				resp, err := createPoll(CreatePollRequest{
+					Title:           "Lenguaje",
+					PollingDuration: 5 * time.Second,
+					PollOptions:     []string{"Español", "Alemán"},
+					AllowedVoters:   []string{"Tyron"},
+					InvitationCount: 1,
+				})
+				if err != nil {
+					t.Fatalf("Failed to create poll: %s\n", err)
+				}
+
+				var pollResponse CreatePollResponse
+				err = json.NewDecoder(resp.Body).Decode(&pollResponse)
+				if err != nil {
+					t.Fatalf("Failed to decode response: %s\n", err)
+				}
+
+				if len(pollResponse.InvitationCodes) != 1 {
+					t.Fatalf("Invitation codes weren't generated! %#v\n", pollResponse)
+				}
+				code := pollResponse.InvitationCodes[0]
+				ranking := map[string]uint{"Español": 1, "Alemán": 2}
+
+				steps := []struct {
+					req    VoteInPollRequest
+					creds  credentials
+					status int
+				}{
+					{VoteInPollRequest{Username: "Pablo"}, credentials{}, http.StatusForbidden},
+					{VoteInPollRequest{Username: "Tyron"}, credentials{}, http.StatusUnauthorized},
+					{VoteInPollRequest{Username: "Tyron"}, credentials{"Tyron", "made up"}, http.StatusUnauthorized},
+					{VoteInPollRequest{Username: "Tyron", InvitationCode: code}, credentials{}, http.StatusUnauthorized},
+					{VoteInPollRequest{Username: "Tyron"}, credentials{"Tyron", "12345"}, http.StatusOK},
+					{VoteInPollRequest{InvitationCode: code}, credentials{}, http.StatusOK},
+					{VoteInPollRequest{InvitationCode: code}, credentials{}, http.StatusForbidden},
+					{VoteInPollRequest{Username: "Pablo", InvitationCode: "made up"}, credentials{}, http.StatusForbidden},
+				}
+				for i, step := range steps {
+					step.req.PollId = pollResponse.PollId
+					step.req.Options = ranking
+					resp, err = voteInPollAs(step.req, step.creds)
+					if err != nil {
+						t.Fatalf("Failed to make request: %s\n", err)
+					}
+
+					if resp.StatusCode != step.status {
+						bodyStr, _ := io.ReadAll(resp.Body)
+						t.Fatalf("Vote %d should have returned %d but returned %d: %s\n", i, step.status, resp.StatusCode, bodyStr)
+					}
+				}
+
+				resp, err = getPollInfo(pollResponse.PollId)
+				if err != nil {
+					t.Fatalf("Failed to get poll info: %s\n", err)
+				}
+
+				bodyBytes, err := io.ReadAll(resp.Body)
+				if err != nil {
+					t.Fatalf("Failed to read body: %s\n", err)
+				}
+
+				if strings.Contains(string(bodyBytes), code) {
+					t.Fatalf("Poll info leaks invitation codes! %s\n", bodyBytes)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			CleanGlobalState()
+			tt.doReq(t)
+		})
+	}
+}
+
+func addWriteIn(pollId uuid.UUID, req AddWriteInRequest, creds credentials) (*http.Response, error) {
+	var reqBody bytes.Buffer
+	err := json.NewEncoder(&reqBody).Encode(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, "/", &reqBody)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.SetPathValue("pollId", pollId.String())
+	creds.setOn(httpReq)
+
+	w := httptest.NewRecorder()
+	response.Handle(AddWriteIn).ServeHTTP(w, httpReq)
+	return w.Result(), nil
+}
+
+func moderateWriteIn(pollId uuid.UUID, optionId uuid.UUID, req ModerateWriteInRequest, creds credentials) (*http.Response, error) {
+	var reqBody bytes.Buffer
+	err := json.NewEncoder(&reqBody).Encode(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, "/", &reqBody)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.SetPathValue("pollId", pollId.String())
+	httpReq.SetPathValue("optionId", optionId.String())
+	creds.setOn(httpReq)
+
+	w := httptest.NewRecorder()
+	response.Handle(ModerateWriteIn).ServeHTTP(w, httpReq)
+	return w.Result(), nil
+}
+
+func TestWriteIns(t *testing.T) {
+	tests := []struct {
+		name  string
+		doReq func(t *testing.T)
+	}{
+		{
+			name: "Approved write-in is unranked on older ballots",
+			doReq: func(t *testing.T) {
+				_, err := createOrLoginUser(CreateOrLoginUserRequest{Username: "Owner", Password: "12345"})
+				if err != nil {
+					t.Fatalf("Failed to create user: %s\n", err)
+				}
+
+				resp, err := createPoll(CreatePollRequest{
+					Title:           "Lenguaje",
+					Username:        "Owner",
+					PollingDuration: 5 * time.Second,
+					PollOptions:     []string{"Español", "Alemán"},
+					AllowWriteIns:   true,
+					WriteInLimit:    1,
+				})
+				if err != nil {
+					t.Fatalf("Failed to create poll: %s\n", err)
+				}
+
+				var pollResponse CreatePollResponse
+				err = json.NewDecoder(resp.Body).Decode(&pollResponse)
+				if err != nil {
+					t.Fatalf("Failed to decode response: %s\n", err)
+				}
+
+				resp, err = voteInPoll(VoteInPollRequest{
+					Username: "Tyron",
+					PollId:   pollResponse.PollId,
+					Options:  map[string]uint{"Español": 1, "Alemán": 2},
+				})
+				if err != nil {
+					t.Fatalf("Failed to make request: %s\n", err)
+				}
+				if resp.StatusCode != http.StatusOK {
+					bodyStr, _ := io.ReadAll(resp.Body)
+					t.Fatalf("Failed to vote before the write-in (%d): %s\n", resp.StatusCode, bodyStr)
+				}
+
+				resp, err = addWriteIn(pollResponse.PollId, AddWriteInRequest{Username: "Tasha", Label: "Francés"}, credentials{})
+				if err != nil {
+					t.Fatalf("Failed to make request: %s\n", err)
+				}
+
+				var writeInResponse AddWriteInResponse
+				err = json.NewDecoder(resp.Body).Decode(&writeInResponse)
+				if err != nil {
+					t.Fatalf("Failed to decode response: %s\n", err)
+				}
+
+				resp, err = addWriteIn(pollResponse.PollId, AddWriteInRequest{Username: "Pablo", Label: "Italiano"}, credentials{})
+				if err != nil {
+					t.Fatalf("Failed to make request: %s\n", err)
+				}
+				if resp.StatusCode == http.StatusOK {
+					t.Fatalf("The write-in limit wasn't respected!\n")
+				}
+
+				resp, err = moderateWriteIn(pollResponse.PollId, writeInResponse.OptionId, ModerateWriteInRequest{Approve: true}, credentials{"Owner", "12345"})
+				if err != nil {
+					t.Fatalf("Failed to make request: %s\n", err)
+				}
+				if resp.StatusCode != http.StatusOK {
+					bodyStr, _ := io.ReadAll(resp.Body)
+					t.Fatalf("Failed to approve write-in (%d): %s\n", resp.StatusCode, bodyStr)
+				}
+
+				resp, err = voteInPoll(VoteInPollRequest{
+					Username: "Yuniqua",
+					PollId:   pollResponse.PollId,
+					Options:  map[string]uint{"Español": 2, "Alemán": 3, "Francés": 1},
+				})
+				if err != nil {
+					t.Fatalf("Failed to make request: %s\n", err)
+				}
+				if resp.StatusCode != http.StatusOK {
+					bodyStr, _ := io.ReadAll(resp.Body)
+					t.Fatalf("Failed to vote for the write-in (%d): %s\n", resp.StatusCode, bodyStr)
+				}
+
+				resp, err = getPollInfo(pollResponse.PollId)
+				if err != nil {
+					t.Fatalf("Failed to get poll info: %s\n", err)
+				}
+
+				var roomInfo Room[int64]
+				err = json.NewDecoder(resp.Body).Decode(&roomInfo)
+				if err != nil {
+					t.Fatalf("Failed to decode body: %s\n", err)
+				}
+
+				if len(roomInfo.Options) != 3 || len(roomInfo.PendingOptions) != 0 {
+					t.Fatalf("The write-in wasn't approved! %#v\n", roomInfo.Options)
+				}
+
+				if len(roomInfo.Votes["Tyron"].Ranking) != 2 {
+					t.Fatalf("Older ballot should leave the write-in unranked! %#v\n", roomInfo.Votes["Tyron"])
+				}
+			},
+		},
+		{
+			name: "Owner write-ins need the credentials to skip moderation",
+			doReq: func(t *testing.T) {
+				_, err := createOrLoginUser(CreateOrLoginUserRequest{Username: "Owner", Password: "12345"})
+				if err != nil {
+					t.Fatalf("Failed to create user: %s\n", err)
+				}
+
+				resp, err := createPoll(CreatePollRequest{
+					Title:           "Lenguaje",
+					Username:        "Owner",
+					PollingDuration: 5 * time.Second,
+					PollOptions:     []string{"Español", "Alemán"},
+					AllowWriteIns:   true,
+				})
+				if err != nil {
+					t.Fatalf("Failed to create poll: %s\n", err)
+				}
+
+				var pollResponse CreatePollResponse
+				err = json.NewDecoder(resp.Body).Decode(&pollResponse)
+				if err != nil {
+					t.Fatalf("Failed to decode response: %s\n", err)
+				}
+
+				resp, err = addWriteIn(pollResponse.PollId, AddWriteInRequest{Username: "Owner", Label: "Francés"}, credentials{})
+				if err != nil {
+					t.Fatalf("Failed to make request: %s\n", err)
+				}
+				if resp.StatusCode != http.StatusOK {
+					bodyStr, _ := io.ReadAll(resp.Body)
+					t.Fatalf("Failed to add write-in (%d): %s\n", resp.StatusCode, bodyStr)
+				}
+
+				resp, err = addWriteIn(pollResponse.PollId, AddWriteInRequest{Username: "Owner", Label: "Italiano"}, credentials{"Owner", "12345"})
+				if err != nil {
+					t.Fatalf("Failed to make request: %s\n", err)
+				}
+				if resp.StatusCode != http.StatusOK {
+					bodyStr, _ := io.ReadAll(resp.Body)
+					t.Fatalf("Failed to add write-in (%d): %s\n", resp.StatusCode, bodyStr)
+				}
+
+				resp, err = getPollInfo(pollResponse.PollId)
+				if err != nil {
+					t.Fatalf("Failed to get poll info: %s\n", err)
+				}
+
+				var roomInfo Room[int64]
+				err = json.NewDecoder(resp.Body).Decode(&roomInfo)
+				if err != nil {
+					t.Fatalf("Failed to decode body: %s\n", err)
+				}
+
+				if len(roomInfo.PendingOptions) != 1 || roomInfo.PendingOptions[0].Label != "Francés" {
+					t.Fatalf("The write-in without credentials should wait for approval! %#v\n", roomInfo.PendingOptions)
+				}
+				if _, found := roomInfo.FindOptionByLabel("Italiano"); !found {
+					t.Fatalf("The write-in of the owner wasn't added! %#v\n", roomInfo.Options)
+				}
+			},
+		},
+		{
+			name: "Write-ins can't be moderated after the poll ends",
+			doReq: func(t *testing.T) {
+				_, err := createOrLoginUser(CreateOrLoginUserRequest{Username: "Owner", Password: "12345"})
+				if err != nil {
+					t.Fatalf("Failed to create user: %s\n", err)
+				}
+
+				resp, err := createPoll(CreatePollRequest{
+					Title:           "Lenguaje",
+					Username:        "Owner",
+					PollingDuration: 5 * time.Second,
+					PollOptions:     []string{"Español", "Alemán"},
+					AllowWriteIns:   true,
+				})
+				if err != nil {
+					t.Fatalf("Failed to create poll: %s\n", err)
+				}
+
+				var pollResponse CreatePollResponse
+				err = json.NewDecoder(resp.Body).Decode(&pollResponse)
+				if err != nil {
+					t.Fatalf("Failed to decode response: %s\n", err)
+				}
+
+				resp, err = addWriteIn(pollResponse.PollId, AddWriteInRequest{Username: "Tasha", Label: "Francés"}, credentials{})
+				if err != nil {
+					t.Fatalf("Failed to make request: %s\n", err)
+				}
+
+				var writeInResponse AddWriteInResponse
+				err = json.NewDecoder(resp.Body).Decode(&writeInResponse)
+				if err != nil {
+					t.Fatalf("Failed to decode response: %s\n", err)
+				}
+
+				room := GlobalState.Rooms[pollResponse.PollId.String()]
+				room.ValidUntil = time.Now().Add(-time.Second)
+				GlobalState.Rooms[pollResponse.PollId.String()] = room
+
+				resp, err = moderateWriteIn(pollResponse.PollId, writeInResponse.OptionId, ModerateWriteInRequest{Approve: true}, credentials{"Owner", "12345"})
+				if err != nil {
+					t.Fatalf("Failed to make request: %s\n", err)
+				}
+				if resp.StatusCode != http.StatusBadRequest {
+					t.Fatalf("Expected %d but got %d\n", http.StatusBadRequest, resp.StatusCode)
+				}
+
+				if len(GlobalState.Rooms[pollResponse.PollId.String()].Options) != 2 {
+					t.Fatalf("The write-in was approved after the poll ended!\n")
+				}
 			},
 		},
 	}
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			CleanGlobalState()
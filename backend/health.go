@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+
+	"github.com/ElrohirGT/RankPoll/response"
+)
+
+type readiness struct {
+	// Set while the polls of AppConfig.StoragePath are loaded.
+	loading      atomic.Bool
+	shuttingDown atomic.Bool
+}
+
+// Readiness tells the orchestrator if requests should be routed to this server.
+var Readiness readiness
+
+func (r *readiness) SetLoading(loading bool) {
+	r.loading.Store(loading)
+}
+
+// SetShuttingDown is called before the server stops accepting connections, it can't be undone.
+func (r *readiness) SetShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+// Check returns why the server isn't ready, nil if it is.
+func (r *readiness) Check() error {
+	switch {
+	case r.shuttingDown.Load():
+		return errors.New("the server is shutting down")
+	case r.loading.Load():
+		return errors.New("the polls are still loading")
+	}
+	return nil
+}
+
+type HealthResponse struct {
+	Status string
+}
+
+// Healthz answers as long as the server is running.
+func Healthz(w http.ResponseWriter, r *http.Request) error {
+	return response.NewResponseBuilder(http.StatusOK).
+		SetBody(HealthResponse{Status: "OK"}).
+		Send(w, r)
+}
+
+// Readyz fails while the polls are loading or the server is shutting down.
+func Readyz(w http.ResponseWriter, r *http.Request) error {
+	if err := Readiness.Check(); err != nil {
+		return response.NewError(http.StatusServiceUnavailable, response.CodeNotReady, "Server not ready!", err)
+	}
+
+	return response.NewResponseBuilder(http.StatusOK).
+		SetBody(HealthResponse{Status: "READY"}).
+		Send(w, r)
+}
+
+type VersionResponse struct {
+	Module    string
+	Version   string
+	GoVersion string
+	Revision  string
+	// Time of the commit the binary was built from, in RFC 3339.
+	RevisionTime string
+	// The binary was built with uncommitted changes.
+	Modified bool
+}
+
+// Version reports the build info embedded in the binary.
+func Version(w http.ResponseWriter, r *http.Request) error {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return response.NewError(http.StatusInternalServerError, response.CodeInternal, "Build info not available!", errors.New("the binary wasn't built with module support"))
+	}
+
+	version := VersionResponse{
+		Module:    info.Main.Path,
+		Version:   info.Main.Version,
+		GoVersion: info.GoVersion,
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			version.Revision = setting.Value
+		case "vcs.time":
+			version.RevisionTime = setting.Value
+		case "vcs.modified":
+			version.Modified = setting.Value == "true"
+		}
+	}
+
+	return response.NewResponseBuilder(http.StatusOK).
+		SetBody(version).
+		Send(w, r)
+}
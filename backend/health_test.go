@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ElrohirGT/RankPoll/response"
+)
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name         string
+		loading      bool
+		shuttingDown bool
+		status       int
+	}{
+		{name: "Ready", status: http.StatusOK},
+		{name: "Loading", loading: true, status: http.StatusServiceUnavailable},
+		{name: "Shutting down", shuttingDown: true, status: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Readiness.loading.Store(tt.loading)
+			Readiness.shuttingDown.Store(tt.shuttingDown)
+			defer Readiness.loading.Store(false)
+			defer Readiness.shuttingDown.Store(false)
+
+			w := httptest.NewRecorder()
+			response.Handle(Readyz).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if w.Code != tt.status {
+				t.Fatalf("Expected %d but got (%d): %s\n", tt.status, w.Code, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ElrohirGT/RankPoll/response"
+	"github.com/google/uuid"
+)
+
+type ImportFormat string
+
+const (
+	ImportBLT ImportFormat = "BLT"
+	// First row has the option labels, optionally after a "Voter" column.
+	// Each following row is a ballot with the rank of each option, empty cells are unranked.
+	ImportCSV ImportFormat = "CSV"
+)
+
+// ImportError is a problem found on a single line of the imported data.
+type ImportError struct {
+	Line uint
+	Msg  string
+}
+
+// importedBallot maps option indexes to ranks.
+type importedBallot struct {
+	Voter   string
+	Line    uint
+	Ranking map[int]uint
+}
+
+type importedPoll struct {
+	Title   string
+	Options []string
+	Ballots []importedBallot
+}
+
+// importLimits bound what the parsers allocate for the untrusted data.
+type importLimits struct {
+	MaxBallots uint
+	MaxOptions uint
+}
+
+func tooManyBallots(limits importLimits) error {
+	return response.NewError(http.StatusBadRequest, response.CodeInvalidImport, "Too many ballots!", fmt.Errorf("can't import more than %d ballots", limits.MaxBallots)).WithField("Data")
+}
+
+// parseBLT fails with an error instead of reporting the line when the data goes over the limits.
+func parseBLT(r io.Reader, limits importLimits) (importedPoll, []ImportError, error) {
+	var poll importedPoll
+	var errs []ImportError
+
+	scanner := bufio.NewScanner(r)
+	var line uint = 0
+	nextLine := func() (string, bool) {
+		for scanner.Scan() {
+			line++
+			text := strings.TrimSpace(scanner.Text())
+			if text != "" {
+				return text, true
+			}
+		}
+		return "", false
+	}
+
+	header, found := nextLine()
+	if !found {
+		return poll, []ImportError{{Line: 1, Msg: "missing candidate and seat count"}}, nil
+	}
+
+	var optionCount, seats int
+	_, err := fmt.Sscanf(header, "%d %d", &optionCount, &seats)
+	if err != nil || optionCount < 2 {
+		return poll, []ImportError{{Line: line, Msg: "invalid candidate and seat count"}}, nil
+	}
+
+	if uint(optionCount) > limits.MaxOptions {
+		return poll, nil, response.NewError(http.StatusBadRequest, response.CodeInvalidImport, "Too many options!", fmt.Errorf("can't import more than %d options", limits.MaxOptions)).WithField("Data")
+	}
+
+	withdrawn := make(map[int]bool)
+	text, found := nextLine()
+	if found && strings.HasPrefix(text, "-") {
+		for field := range strings.FieldsSeq(text) {
+			candidate, err := strconv.Atoi(field)
+			if err != nil || candidate >= 0 || -candidate > optionCount {
+				errs = append(errs, ImportError{Line: line, Msg: fmt.Sprintf("invalid withdrawn candidate %s", field)})
+				continue
+			}
+			withdrawn[-candidate-1] = true
+		}
+		text, found = nextLine()
+	}
+
+	for ; found && text != "0"; text, found = nextLine() {
+		fields := strings.Fields(text)
+		weight, err := strconv.Atoi(fields[0])
+		if err != nil || weight < 1 {
+			errs = append(errs, ImportError{Line: line, Msg: fmt.Sprintf("invalid ballot weight %s", fields[0])})
+			continue
+		}
+
+		if fields[len(fields)-1] != "0" {
+			errs = append(errs, ImportError{Line: line, Msg: "ballot doesn't end with 0"})
+			continue
+		}
+
+		ballot := importedBallot{Line: line, Ranking: make(map[int]uint)}
+		var position uint = 0
+		for _, field := range fields[1 : len(fields)-1] {
+			position++
+			for candidateStr := range strings.SplitSeq(field, "=") {
+				candidate, err := strconv.Atoi(candidateStr)
+				if err != nil || candidate < 1 || candidate > optionCount {
+					errs = append(errs, ImportError{Line: line, Msg: fmt.Sprintf("invalid candidate %s", candidateStr)})
+					continue
+				}
+
+				if _, repeated := ballot.Ranking[candidate-1]; repeated {
+					errs = append(errs, ImportError{Line: line, Msg: fmt.Sprintf("candidate %d is ranked twice", candidate)})
+					continue
+				}
+
+				if !withdrawn[candidate-1] {
+					ballot.Ranking[candidate-1] = position
+				}
+			}
+		}
+
+		if uint(weight) > limits.MaxBallots-uint(len(poll.Ballots)) {
+			return poll, nil, tooManyBallots(limits)
+		}
+
+		for range weight {
+			poll.Ballots = append(poll.Ballots, ballot)
+		}
+	}
+
+	if !found {
+		errs = append(errs, ImportError{Line: line, Msg: "missing 0 after the ballots"})
+		return poll, errs, nil
+	}
+
+	names := make([]string, 0, optionCount+1)
+	for range optionCount + 1 {
+		text, found := nextLine()
+		if !found {
+			errs = append(errs, ImportError{Line: line, Msg: "missing candidate names or title"})
+			return poll, errs, nil
+		}
+
+		name, err := strconv.Unquote(text)
+		if err != nil {
+			name = strings.Trim(text, `"`)
+		}
+		names = append(names, name)
+	}
+
+	poll.Options = names[:optionCount]
+	poll.Title = names[optionCount]
+	return poll, errs, nil
+}
+
+func parseCSV(r io.Reader, limits importLimits) (importedPoll, []ImportError, error) {
+	var poll importedPoll
+	var errs []ImportError
+
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return poll, []ImportError{{Line: 1, Msg: fmt.Sprintf("invalid header: %s", err)}}, nil
+	}
+
+	hasVoter := len(header) > 0 && strings.EqualFold(header[0], "Voter")
+	if hasVoter {
+		header = header[1:]
+	}
+	poll.Options = header
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			errs = append(errs, ImportError{Line: uint(parseErr.Line), Msg: parseErr.Err.Error()})
+			continue
+		} else if err != nil {
+			errs = append(errs, ImportError{Msg: err.Error()})
+			break
+		}
+		line, _ := reader.FieldPos(0)
+
+		if uint(len(poll.Ballots)) >= limits.MaxBallots {
+			return poll, nil, tooManyBallots(limits)
+		}
+
+		ballot := importedBallot{Line: uint(line), Ranking: make(map[int]uint)}
+		if hasVoter {
+			ballot.Voter = record[0]
+			record = record[1:]
+		}
+
+		for i, cell := range record {
+			if cell == "" {
+				continue
+			}
+
+			position, err := strconv.ParseUint(cell, 10, 0)
+			if err != nil || position == 0 {
+				errs = append(errs, ImportError{Line: uint(line), Msg: fmt.Sprintf("invalid rank %s for option %s", cell, header[i])})
+				continue
+			}
+			ballot.Ranking[i] = uint(position)
+		}
+		poll.Ballots = append(poll.Ballots, ballot)
+	}
+
+	return poll, errs, nil
+}
+
+// toRoom creates a closed room with the imported ballots already cast.
+func (p importedPoll) toRoom(owner string) (Room[time.Time], []ImportError) {
+	var errs []ImportError
+
+	room := Room[time.Time]{
+		Id:         uuid.New(),
+		Title:      p.Title,
+		Owner:      owner,
+		Options:    make([]Option, 0, len(p.Options)),
+		Votes:      make(map[string]Vote, len(p.Ballots)),
+		ValidUntil: time.Now(),
+	}
+
+	labels := make(map[string]bool, len(p.Options))
+	for _, label := range p.Options {
+		if label == "" || labels[label] {
+			errs = append(errs, ImportError{Line: 1, Msg: fmt.Sprintf("option %q is empty or repeated", label)})
+			continue
+		}
+		labels[label] = true
+		room.Options = append(room.Options, Option{Id: uuid.New(), Label: label})
+	}
+
+	if len(room.Options) < 2 {
+		errs = append(errs, ImportError{Line: 1, Msg: "a poll needs at least 2 options"})
+	}
+
+	if len(errs) > 0 {
+		return room, errs
+	}
+
+	for i, ballot := range p.Ballots {
+		ranking := make([]Rank, 0, len(ballot.Ranking))
+		for optIdx, position := range ballot.Ranking {
+			if position > uint(len(room.Options)) {
+				errs = append(errs, ImportError{Line: ballot.Line, Msg: fmt.Sprintf("option %s has a rank greater than voting options", room.Options[optIdx].Label)})
+				continue
+			}
+
+			ranking = append(ranking, Rank{OptionId: room.Options[optIdx].Id, Position: position})
+		}
+
+		voter := ballot.Voter
+		if voter == "" {
+			voter = fmt.Sprintf("imported-%d", i+1)
+		}
+
+		if _, repeated := room.Votes[voter]; repeated {
+			errs = append(errs, ImportError{Line: ballot.Line, Msg: fmt.Sprintf("voter %s already voted", voter)})
+			continue
+		}
+		room.Votes[voter] = Vote{Username: ballot.Voter, Ranking: ranking}
+	}
+
+	return room, errs
+}
+
+type ImportPollRequest struct {
+	Username string
+	// Overrides the title found on the data.
+	Title  string
+	Format ImportFormat
+	Data   string
+}
+
+type ImportPollResponse struct {
+	PollId uuid.UUID
+	Msg    string
+	// Only set when the import fails.
+	Code   response.ErrorCode
+	Errors []ImportError
+}
+
+// ImportPoll creates a closed poll from ballots counted outside RankPoll.
+// If any line of the data has errors nothing is imported.
+func ImportPoll(w http.ResponseWriter, r *http.Request) error {
+	// The imported ballots are as secret as the ones voted here.
+	OmitRequestBody(r)
+
+	var req ImportPollRequest
+	if err := response.DecodeJSON(r, &req); err != nil {
+		return err
+	}
+
+	limits := importLimits{
+		MaxBallots: AppConfig.Limits.MaxImportedBallots,
+		// Each option needs its own line on the data.
+		MaxOptions: uint(strings.Count(req.Data, "\n")) + 1,
+	}
+
+	var poll importedPoll
+	var errs []ImportError
+	var err error
+	switch req.Format {
+	case ImportBLT:
+		poll, errs, err = parseBLT(strings.NewReader(req.Data), limits)
+	case ImportCSV:
+		poll, errs, err = parseCSV(strings.NewReader(req.Data), limits)
+	default:
+		return response.NewError(http.StatusBadRequest, response.CodeInvalidImport, "Invalid import format!", fmt.Errorf("unknown import format %s", req.Format)).WithField("Format")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if req.Title != "" {
+		poll.Title = req.Title
+	}
+
+	room, roomErrs := poll.toRoom(req.Username)
+	errs = append(errs, roomErrs...)
+	if len(errs) > 0 {
+		return response.NewResponseBuilder(http.StatusBadRequest).
+			SetBody(ImportPollResponse{Msg: "Failed to import poll!", Code: response.CodeInvalidImport, Errors: errs}).
+			Send(w, r)
+	}
+
+	computeSummary(r.Context(), &room)
+	response.Logger(r.Context()).Info("Imported poll", "poll_id", room.Id, "username", req.Username)
+
+	GlobalState.Lock.Lock()
+	defer GlobalState.Lock.Unlock()
+	GlobalState.Rooms[room.Id.String()] = room
+
+	return response.NewResponseBuilder(http.StatusOK).
+		SetBody(ImportPollResponse{PollId: room.Id, Msg: "Success!"}).
+		Send(w, r)
+}
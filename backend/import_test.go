@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ElrohirGT/RankPoll/response"
+	"github.com/google/uuid"
+)
+
+var testImportLimits = importLimits{MaxBallots: 5, MaxOptions: 3}
+
+func TestParseBLT(t *testing.T) {
+	teacher := Option{Id: uuid.New(), Label: "Teacher"}
+	doctor := Option{Id: uuid.New(), Label: "Doctor"}
+	exported := Room[time.Time]{
+		Title:   "Favorite Profesion?",
+		Options: []Option{teacher, doctor},
+		Votes: map[string]Vote{
+			"Tyron": {Username: "Tyron", Ranking: []Rank{
+				{OptionId: teacher.Id, Position: 2},
+				{OptionId: doctor.Id, Position: 1},
+			}},
+		},
+	}
+
+	var blt strings.Builder
+	err := writeBLT(&blt, exported, false)
+	if err != nil {
+		t.Fatalf("Failed to write BLT: %s\n", err)
+	}
+
+	poll, errs, err := parseBLT(strings.NewReader(blt.String()+"\n"), testImportLimits)
+	if err != nil || len(errs) > 0 {
+		t.Fatalf("Failed to parse BLT: %v %#v\n", err, errs)
+	}
+
+	room, errs := poll.toRoom("")
+	if len(errs) > 0 {
+		t.Fatalf("Failed to create room: %#v\n", errs)
+	}
+
+	computeSummary(t.Context(), &room)
+	if room.Title != exported.Title || room.Summary.Winner != "Doctor" {
+		t.Fatalf("Imported poll doesn't match the exported one!\n%#v", room)
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		errorLines []uint
+	}{
+		{
+			name: "Valid rankings",
+			data: "Voter,Teacher,Doctor,Plumber\nTyron,2,1,3\nYuniqua,1,,2\n",
+		},
+		{
+			name:       "Invalid rows are reported",
+			data:       "Teacher,Doctor\n1,2\n0,1\n1\n2,x\n",
+			errorLines: []uint{3, 4, 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poll, errs, err := parseCSV(strings.NewReader(tt.data), testImportLimits)
+			if err != nil {
+				t.Fatalf("Failed to parse CSV: %s\n", err)
+			}
+
+			if len(errs) != len(tt.errorLines) {
+				t.Fatalf("Expected %d errors but got %#v\n", len(tt.errorLines), errs)
+			}
+
+			for i, line := range tt.errorLines {
+				if errs[i].Line != line {
+					t.Fatalf("Expected error on line %d but got %#v\n", line, errs[i])
+				}
+			}
+
+			if len(errs) > 0 {
+				return
+			}
+
+			_, errs = poll.toRoom("")
+			if len(errs) > 0 {
+				t.Fatalf("Failed to create room: %#v\n", errs)
+			}
+		})
+	}
+}
+
+func TestImportLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		format ImportFormat
+		data   string
+	}{
+		{name: "BLT weight over the ballot limit", format: ImportBLT, data: "2 1\n1 1 2 0\n9223372036854775807 2 1 0\n0\n\"Teacher\"\n\"Doctor\"\n\"Profesion\"\n"},
+		{name: "BLT candidates over the option limit", format: ImportBLT, data: "9223372036854775807 1\n1 1 2 0\n0\n\"Teacher\"\n\"Doctor\"\n\"Profesion\"\n"},
+		{name: "CSV rows over the ballot limit", format: ImportCSV, data: "Teacher,Doctor\n1,2\n2,1\n1,2\n2,1\n1,2\n2,1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.format == ImportBLT {
+				_, _, err = parseBLT(strings.NewReader(tt.data), testImportLimits)
+			} else {
+				_, _, err = parseCSV(strings.NewReader(tt.data), testImportLimits)
+			}
+
+			var respErr *response.Error
+			if !errors.As(err, &respErr) || respErr.Status != http.StatusBadRequest {
+				t.Fatalf("Expected a bad request error but got: %v\n", err)
+			}
+		})
+	}
+}
@@ -2,87 +2,182 @@ package main
 
 import (
 	"context"
-	"log"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"sync"
 	"syscall"
 	"time"
 )
 
-type Params struct {
-	Addr string
+type LoginFailures struct {
+	Count       uint
+	LockedUntil time.Time
 }
 
 type State struct {
 	Lock  *sync.RWMutex
 	Users map[string]string
-	Rooms map[string]Room[time.Time]
+	// Failed logins of each user since their last successful one.
+	LoginFailures map[string]LoginFailures
+	Rooms         map[string]Room[time.Time]
 }
 
 var GlobalState = State{
-	Lock:  &sync.RWMutex{},
-	Users: make(map[string]string),
-	Rooms: make(map[string]Room[time.Time]),
+	Lock:          &sync.RWMutex{},
+	Users:         make(map[string]string),
+	LoginFailures: make(map[string]LoginFailures),
+	Rooms:         make(map[string]Room[time.Time]),
 }
 
 func CleanGlobalState() {
-	log.Println("Cleaning global state...")
+	slog.Debug("Cleaning global state...")
 	clear(GlobalState.Users)
+	clear(GlobalState.LoginFailures)
 	clear(GlobalState.Rooms)
-	log.Println("DONE!")
+	slog.Debug("DONE!")
 }
 
 func main() {
-	params := ParseParams()
+	config, err := LoadConfig(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		slog.Error("Invalid configuration", "error", err)
+		os.Exit(2)
+	}
+	AppConfig = config
+	slog.SetDefault(NewLogger(os.Stderr, config.Log.Format, config.Log.Level))
 
 	router := http.NewServeMux()
 	MountHandlers(router)
 	withMiddlewares := ApplyMiddlewares(router,
-		RequestLoggerMiddleware,
-		CORSMiddleware,
+		RequestIdMiddleware,
+		RequestLoggerMiddleware(RequestLoggerConfig{
+			MaxBodyBytes:   config.Log.BodyLimit,
+			RedactedFields: slices.Concat(DefaultRedactedFields, config.Log.RedactFields),
+		}),
+		RecoveryMiddleware,
+		CORSMiddleware(config.CORS, router),
+		MaxBodyMiddleware(config.Limits.MaxBodyBytes),
 	)
 
 	srv := &http.Server{
-		Addr:    params.Addr,
-		Handler: withMiddlewares,
+		Addr:              config.Server.Addr,
+		Handler:           withMiddlewares,
+		ReadTimeout:       time.Duration(config.Server.ReadTimeout),
+		ReadHeaderTimeout: time.Duration(config.Server.ReadHeaderTimeout),
+		WriteTimeout:      time.Duration(config.Server.WriteTimeout),
+		IdleTimeout:       time.Duration(config.Server.IdleTimeout),
+	}
+	srv.RegisterOnShutdown(PollEvents.Close)
+
+	var certs *certReloader
+	if config.Server.TLSCertFile != "" {
+		certs, err = newCertReloader(config.Server.TLSCertFile, config.Server.TLSKeyFile)
+		if err != nil {
+			slog.Error("Failed to load TLS certificate", "error", err)
+			os.Exit(2)
+		}
+		srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+		}
+	}
+
+	listener, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		slog.Error("Failed to listen", "address", srv.Addr, "error", err)
+		os.Exit(1)
 	}
 
+	// The snapshot is loaded while listening, so the orchestrator sees /readyz failing instead of a dead server.
+	Readiness.SetLoading(config.StoragePath != "")
 	go func() {
-		log.Println("Listening on:", srv.Addr)
-		if err := srv.ListenAndServe(); err != nil {
-			log.Println(err)
+		slog.Info("Listening", "address", listener.Addr(), "tls", certs != nil)
+
+		var err error
+		if certs != nil {
+			err = srv.ServeTLS(listener, "", "")
+		} else {
+			err = srv.Serve(listener)
+		}
+		if err != nil {
+			slog.Info("Server stopped", "error", err)
 		}
 	}()
 
+	if config.StoragePath != "" {
+		if err := LoadSnapshot(config.StoragePath); err != nil {
+			slog.Error("Failed to load the polls", "storage_path", config.StoragePath, "error", err)
+			os.Exit(1)
+		}
+		slog.Info("Loaded the polls", "storage_path", config.StoragePath, "polls", len(GlobalState.Rooms))
+		Readiness.SetLoading(false)
+	}
+
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
-	<-c
+	for sig := <-c; sig == syscall.SIGHUP; sig = <-c {
+		if certs == nil {
+			slog.Info("Received SIGHUP without TLS, nothing to reload")
+			continue
+		}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
-	defer cancel()
+		if err := certs.Reload(); err != nil {
+			slog.Error("Failed to reload TLS certificate, keeping the previous one", "error", err)
+		} else {
+			slog.Info("Reloaded TLS certificate")
+		}
+	}
 
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Panicf("Failed to shutdown server: %s", err)
+	Readiness.SetShuttingDown()
+	slog.Info("Draining...", "delay", time.Duration(config.Server.DrainDelay))
+	select {
+	case <-time.After(time.Duration(config.Server.DrainDelay)):
+	case <-c:
+		slog.Info("Received another signal, skipping the drain")
 	}
 
-	log.Println("Shut down... Goodbye!")
-}
+	slog.Info("Shutting down...")
 
-func ParseParams() Params {
-	params := Params{}
-	params.Addr, _ = LookUpParam("SRV_ADDRESS")
-	return params
-}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.Server.ShutdownGrace))
+	defer cancel()
 
-func LookUpParam(key string) (string, bool) {
-	const ParamNotFoundFormat = "WARNING: %s ENV VARIABLE NOT SET!\n"
+	// The polls are saved even if some requests didn't finish in time.
+	shutdownErr := srv.Shutdown(ctx)
+	if shutdownErr != nil {
+		slog.Error("Failed to shutdown server", "error", shutdownErr)
+	}
 
-	param, found := os.LookupEnv(key)
-	if !found {
-		log.Printf(ParamNotFoundFormat, key)
+	if config.StoragePath != "" {
+		if err := SaveSnapshot(config.StoragePath); err != nil {
+			slog.Error("Failed to save the polls", "storage_path", config.StoragePath, "error", err)
+			os.Exit(1)
+		}
+		slog.Info("Saved the polls", "storage_path", config.StoragePath)
+	}
+
+	if shutdownErr != nil {
+		os.Exit(1)
 	}
 
-	return param, found
+	slog.Info("Shut down... Goodbye!")
+}
+
+// NewLogger creates the logger of the server, format is either "text" or "json".
+func NewLogger(w io.Writer, format string, level slog.Level) *slog.Logger {
+	options := &slog.HandlerOptions{Level: level}
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(w, options))
+	}
+	return slog.New(slog.NewTextHandler(w, options))
 }
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ElrohirGT/RankPoll/metrics"
+)
+
+var Metrics = metrics.NewRegistry()
+
+var (
+	requestsTotal = Metrics.NewCounter("rankpoll_http_requests_total",
+		"Requests handled by route, method and status code.", "route", "method", "code")
+	requestDuration = Metrics.NewHistogram("rankpoll_http_request_duration_seconds",
+		"Time taken to handle requests by route.", metrics.DefaultBuckets, "route")
+	votesCast = Metrics.NewCounter("rankpoll_votes_cast_total",
+		"Ballots accepted in all polls.")
+	summaryDuration = Metrics.NewHistogram("rankpoll_summary_duration_seconds",
+		"Time taken to compute the summary of a poll.", metrics.DefaultBuckets)
+	loginFailures = Metrics.NewCounter("rankpoll_login_failures_total",
+		"Logins rejected because of invalid credentials.")
+	_ = Metrics.NewGaugeFunc("rankpoll_active_polls",
+		"Polls that are still accepting votes.", func() float64 { return float64(countPolls(true)) })
+	_ = Metrics.NewGaugeFunc("rankpoll_closed_polls",
+		"Polls that no longer accept votes.", func() float64 { return float64(countPolls(false)) })
+)
+
+// countPolls counts the polls that are open or closed right now.
+func countPolls(open bool) int {
+	now := time.Now()
+
+	GlobalState.Lock.RLock()
+	defer GlobalState.Lock.RUnlock()
+
+	count := 0
+	for _, room := range GlobalState.Rooms {
+		if now.After(room.ValidUntil) != open {
+			count++
+		}
+	}
+	return count
+}
+
+// routePath removes the method of the pattern.
+func routePath(pattern string) string {
+	if _, path, found := strings.Cut(pattern, " "); found {
+		return path
+	}
+	return pattern
+}
+
+// InstrumentRoute records the count and latency of the requests to the route,
+// the route is the pattern it's mounted with without the method.
+func InstrumentRoute(pattern string, next http.Handler) http.Handler {
+	route := routePath(pattern)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		lw := &loggingWriter{ResponseWriter: w}
+		next.ServeHTTP(lw, r)
+
+		if lw.status == 0 {
+			lw.status = http.StatusOK
+		}
+		requestsTotal.Inc(route, r.Method, strconv.Itoa(lw.status))
+		requestDuration.Observe(time.Since(start).Seconds(), route)
+	})
+}
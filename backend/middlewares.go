@@ -2,10 +2,17 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"io"
-	"log"
 	"net/http"
-	"net/http/httptest"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ElrohirGT/RankPoll/response"
+	"github.com/google/uuid"
 )
 
 type Middleware = func(http.Handler) http.Handler
@@ -19,57 +26,234 @@ func ApplyMiddlewares(mux http.Handler, mds ...Middleware) http.Handler {
 	return finalMux
 }
 
-func CORSMiddleware(next http.Handler) http.Handler {
+// MaxRequestIdLength bounds the incoming ids that are reused, longer ones are replaced.
+const MaxRequestIdLength = 128
+
+// RequestIdMiddleware assigns an id to each request, reusing the X-Request-ID the client sent if it's valid.
+// The id is echoed back and added to every log of the request.
+func RequestIdMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Add("Access-Control-Allow-Origin", "*")
-		w.Header().Add("Vary", "Origin")
-		w.Header().Add("Vary", "Access-Control-Request-Method")
-		w.Header().Add("Vary", "Access-Control-Request-Headers")
-		w.Header().Add("Access-Control-Allow-Headers", "Content-Type, Origin, Accept, token")
-		w.Header().Add("Access-Control-Allow-Methods", "GET,POST,OPTIONS")
-
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
+		id := r.Header.Get(response.RequestIdHeader)
+		if !isValidRequestId(id) {
+			id = uuid.NewString()
 		}
 
-		next.ServeHTTP(w, r)
+		w.Header().Set(response.RequestIdHeader, id)
+		next.ServeHTTP(w, r.WithContext(response.WithRequestId(r.Context(), id)))
 	})
 }
 
-func RequestLoggerMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		body, err := io.ReadAll(r.Body)
-		if err != nil {
-			log.Printf("Failed to read request body: %s\n", err)
-		} else {
-			log.Printf("[REQ-%s %s] %s\n", r.Method, r.URL.String(), body)
+// isValidRequestId only accepts ids that are safe to write to the logs.
+func isValidRequestId(id string) bool {
+	if id == "" || len(id) > MaxRequestIdLength {
+		return false
+	}
+
+	for _, c := range id {
+		isAlphanumeric := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+		if !isAlphanumeric && !strings.ContainsRune("-_.:", c) {
+			return false
 		}
+	}
+	return true
+}
 
-		newBodyStream := io.NopCloser(bytes.NewReader(body))
-		r.Body = newBodyStream
+// RecoveryMiddleware sends a panic of the next handlers as a 500 instead of dropping the connection.
+func RecoveryMiddleware(next http.Handler) http.Handler {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		next.ServeHTTP(w, r)
+		return nil
+	})
+}
 
-		rec := httptest.NewRecorder()
-		next.ServeHTTP(rec, r)
+// MaxBodyMiddleware rejects request bodies over the limit with 413.
+func MaxBodyMiddleware(limit int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				response.WriteError(w, r, response.NewError(http.StatusRequestEntityTooLarge, response.CodeBodyTooLarge, "Body too large!", fmt.Errorf("the body can't be larger than %d bytes", limit)))
+				return
+			}
 
-		resp := rec.Result()
-		body, err = io.ReadAll(resp.Body)
-		if err != nil {
-			log.Printf("Failed to read response body: %s\n", err)
-		} else {
-			log.Printf("[RESP-%s] %s\n", resp.Status, body)
-		}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Fields that are never logged, matched case insensitively at any depth of a JSON body.
+var DefaultRedactedFields = []string{"Password", "Token", "InvitationCode", "InvitationCodes"}
 
-		for key, val := range resp.Header {
-			for _, v := range val {
-				w.Header().Add(key, v)
+type omitBodyKey struct{}
+
+// OmitRequestBody keeps the body of the request out of the request log,
+// for bodies that are secret as a whole like ballots.
+func OmitRequestBody(r *http.Request) {
+	if omit, ok := r.Context().Value(omitBodyKey{}).(*bool); ok {
+		*omit = true
+	}
+}
+
+type RequestLoggerConfig struct {
+	// Bodies longer than this are truncated, 0 doesn't log bodies.
+	MaxBodyBytes   int
+	RedactedFields []string
+}
+
+// RequestLoggerMiddleware logs each request and its response without buffering them,
+// so streamed responses are still flushed to the client as they're written.
+func RequestLoggerMiddleware(config RequestLoggerConfig) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			reqBody := &loggedBody{limit: config.MaxBodyBytes}
+			if r.Body != nil {
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.TeeReader(r.Body, reqBody), r.Body}
 			}
+
+			omitBody := false
+			r = r.WithContext(context.WithValue(r.Context(), omitBodyKey{}, &omitBody))
+
+			lw := &loggingWriter{ResponseWriter: w, body: loggedBody{limit: config.MaxBodyBytes}}
+			next.ServeHTTP(lw, r)
+
+			if lw.status == 0 {
+				lw.status = http.StatusOK
+			}
+
+			// Handlers decode JSON whatever the Content-Type says, so request bodies
+			// are only logged if they parse as JSON and their fields can be redacted.
+			loggedReqBody := reqBody.String(response.MediaJSON, config.RedactedFields)
+			if omitBody && loggedReqBody != "" {
+				loggedReqBody = "<secret body not logged>"
+			}
+
+			response.Logger(r.Context()).Info("Request handled",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", lw.status,
+				"duration", time.Since(start),
+				"request_bytes", reqBody.size,
+				"response_bytes", lw.body.size,
+				"request_body", loggedReqBody,
+				"response_body", lw.body.String(lw.Header().Get("Content-Type"), config.RedactedFields),
+			)
+		})
+	}
+}
+
+// loggedBody counts the bytes written to it, keeping only the first ones.
+type loggedBody struct {
+	limit int
+	size  int
+	buf   bytes.Buffer
+}
+
+func (b *loggedBody) Write(p []byte) (int, error) {
+	b.size += len(p)
+	if remaining := b.limit - b.buf.Len(); remaining > 0 {
+		b.buf.Write(p[:min(len(p), remaining)])
+	}
+	return len(p), nil
+}
+
+// String formats the body to be logged.
+// JSON is only logged if it's complete, so redacted fields can't slip through a truncated body.
+func (b *loggedBody) String(contentType string, redactedFields []string) string {
+	if b.size == 0 || b.limit <= 0 {
+		return ""
+	}
+
+	truncated := b.size > b.buf.Len()
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(mediaType)
+
+	switch {
+	case mediaType == response.MediaJSON || mediaType == "":
+		if truncated {
+			return "<truncated JSON not logged>"
 		}
-		w.WriteHeader(resp.StatusCode)
 
-		_, err = io.Copy(w, bytes.NewBuffer(body))
+		redacted, err := redactJSON(b.buf.Bytes(), redactedFields)
 		if err != nil {
-			log.Printf("Failed to copy body bytes into response: %s\n", err)
+			return "<invalid JSON not logged>"
 		}
-	})
+		return string(redacted)
+
+	case strings.HasPrefix(mediaType, "text/") && mediaType != "text/event-stream":
+		if truncated {
+			return b.buf.String() + "..."
+		}
+		return b.buf.String()
+	}
+
+	return fmt.Sprintf("<%s not logged>", mediaType)
+}
+
+// redactJSON replaces the values of the redacted fields of the JSON document.
+func redactJSON(body []byte, redactedFields []string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(redactValue(value, redactedFields))
+}
+
+func redactValue(value any, redactedFields []string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			isRedacted := slices.ContainsFunc(redactedFields, func(f string) bool {
+				return strings.EqualFold(f, key)
+			})
+			if isRedacted {
+				v[key] = "[REDACTED]"
+			} else {
+				v[key] = redactValue(field, redactedFields)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactValue(item, redactedFields)
+		}
+	}
+	return value
+}
+
+// loggingWriter records the status and body of the response as it's written to the client.
+type loggingWriter struct {
+	http.ResponseWriter
+	status int
+	body   loggedBody
+}
+
+func (l *loggingWriter) WriteHeader(status int) {
+	if l.status == 0 {
+		l.status = status
+	}
+	l.ResponseWriter.WriteHeader(status)
+}
+
+func (l *loggingWriter) Write(b []byte) (int, error) {
+	if l.status == 0 {
+		l.status = http.StatusOK
+	}
+	n, err := l.ResponseWriter.Write(b)
+	l.body.Write(b[:n])
+	return n, err
+}
+
+func (l *loggingWriter) Flush() {
+	_ = http.NewResponseController(l.ResponseWriter).Flush()
+}
+
+func (l *loggingWriter) Unwrap() http.ResponseWriter {
+	return l.ResponseWriter
 }
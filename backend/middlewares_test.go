@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ElrohirGT/RankPoll/response"
+	"github.com/google/uuid"
+)
+
+func TestRequestIdMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		incomingId string
+		expectSame bool
+	}{
+		{name: "Generates id", incomingId: "", expectSame: false},
+		{name: "Reuses incoming id", incomingId: "proxy-1234.abc", expectSame: true},
+		{name: "Replaces unsafe id", incomingId: "bad id\nINJECTED", expectSame: false},
+		{name: "Replaces long id", incomingId: strings.Repeat("a", MaxRequestIdLength+1), expectSame: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var contextId string
+			handler := RequestIdMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contextId = response.RequestId(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incomingId != "" {
+				req.Header.Set(response.RequestIdHeader, tt.incomingId)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			echoedId := w.Header().Get(response.RequestIdHeader)
+			if echoedId == "" || echoedId != contextId {
+				t.Fatalf("Expected the echoed id `%s` to match the context id `%s`\n", echoedId, contextId)
+			}
+
+			if (echoedId == tt.incomingId) != tt.expectSame {
+				t.Fatalf("Expected reusing `%s` to be %t but got `%s`\n", tt.incomingId, tt.expectSame, echoedId)
+			}
+		})
+	}
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	handler := ApplyMiddlewares(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("oops")
+	}), RequestIdMiddleware, RecoveryMiddleware)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected 500 but got %d\n", w.Code)
+	}
+
+	if w.Header().Get(response.RequestIdHeader) == "" {
+		t.Fatalf("Expected the request id to be sent with the error\n")
+	}
+
+	var errResponse response.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&errResponse); err != nil {
+		t.Fatalf("Failed to decode error: %s\n", err)
+	}
+
+	if errResponse.Code != response.CodeInternal {
+		t.Fatalf("Expected %s code but got %s\n", response.CodeInternal, errResponse.Code)
+	}
+}
+
+func TestRequestLoggerMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		handler     http.HandlerFunc
+		doCheck     func(t *testing.T, logs string, w *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Redacts passwords",
+			body: `{"Username":"FAGD","Password":"hunter2"}`,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.Copy(io.Discard, r.Body)
+				w.Header().Set("Content-Type", response.MediaJSON)
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(`{"InvitationCodes":["secret"],"Msg":"ok"}`))
+			},
+			doCheck: func(t *testing.T, logs string, w *httptest.ResponseRecorder) {
+				if strings.Contains(logs, "hunter2") || strings.Contains(logs, "secret") {
+					t.Fatalf("Expected secrets to be redacted but got:\n%s\n", logs)
+				}
+
+				if !strings.Contains(logs, "FAGD") || !strings.Contains(logs, `"status":201`) {
+					t.Fatalf("Expected the request to be logged but got:\n%s\n", logs)
+				}
+
+				if w.Code != http.StatusCreated || w.Body.String() != `{"InvitationCodes":["secret"],"Msg":"ok"}` {
+					t.Fatalf("Expected the response to be untouched but got (%d): %s\n", w.Code, w.Body)
+				}
+			},
+		},
+		{
+			name: "Doesn't log truncated JSON",
+			body: `{"Username":"FAGD","Password":"` + strings.Repeat("a", 100) + `"}`,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.Copy(io.Discard, r.Body)
+			},
+			doCheck: func(t *testing.T, logs string, w *httptest.ResponseRecorder) {
+				if strings.Contains(logs, "Password") {
+					t.Fatalf("Expected the truncated body to not be logged but got:\n%s\n", logs)
+				}
+			},
+		},
+		{
+			name:        "Redacts JSON sent as text",
+			body:        `{"Username":"FAGD","Password":"hunter2"}`,
+			contentType: "text/plain",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.Copy(io.Discard, r.Body)
+			},
+			doCheck: func(t *testing.T, logs string, w *httptest.ResponseRecorder) {
+				if strings.Contains(logs, "hunter2") {
+					t.Fatalf("Expected the password to be redacted but got:\n%s\n", logs)
+				}
+			},
+		},
+		{
+			name:        "Doesn't log text bodies",
+			body:        `Password=hunter2`,
+			contentType: "text/plain",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.Copy(io.Discard, r.Body)
+			},
+			doCheck: func(t *testing.T, logs string, w *httptest.ResponseRecorder) {
+				if strings.Contains(logs, "hunter2") {
+					t.Fatalf("Expected the body to not be logged but got:\n%s\n", logs)
+				}
+			},
+		},
+		{
+			name: "Doesn't log imported ballots",
+			body: `{"Format":"CSV","Data":"Tyron,A,B"}`,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				defer CleanGlobalState()
+				response.Handle(ImportPoll).ServeHTTP(w, r)
+			},
+			doCheck: func(t *testing.T, logs string, w *httptest.ResponseRecorder) {
+				if strings.Contains(logs, "Tyron") {
+					t.Fatalf("Expected the ballots to not be logged but got:\n%s\n", logs)
+				}
+			},
+		},
+		{
+			name: "Doesn't log ballots",
+			body: `{"Username":"Tyron","Options":{"A":1,"B":2}}`,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				pollId := uuid.New()
+				GlobalState.Rooms[pollId.String()] = Room[time.Time]{
+					Id:           pollId,
+					Options:      []Option{{Id: uuid.New(), Label: "A"}, {Id: uuid.New(), Label: "B"}},
+					SecretBallot: true,
+					Votes:        map[string]Vote{},
+					ValidUntil:   time.Now().Add(time.Minute),
+				}
+				defer CleanGlobalState()
+
+				r.SetPathValue("pollId", pollId.String())
+				response.Handle(VoteInPoll).ServeHTTP(w, r)
+			},
+			doCheck: func(t *testing.T, logs string, w *httptest.ResponseRecorder) {
+				if w.Code != http.StatusOK {
+					t.Fatalf("Failed to vote (%d): %s\n", w.Code, w.Body)
+				}
+
+				if strings.Contains(logs, "Tyron") || strings.Contains(logs, `"A":1`) {
+					t.Fatalf("Expected the ballot to not be logged but got:\n%s\n", logs)
+				}
+			},
+		},
+		{
+			name: "Streams responses",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				_, _ = w.Write([]byte("data: hello\n\n"))
+				w.(http.Flusher).Flush()
+			},
+			doCheck: func(t *testing.T, logs string, w *httptest.ResponseRecorder) {
+				if !w.Flushed {
+					t.Fatalf("Expected the response to be flushed\n")
+				}
+
+				if strings.Contains(logs, "hello") {
+					t.Fatalf("Expected the event stream to not be logged but got:\n%s\n", logs)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs strings.Builder
+			defer slog.SetDefault(slog.Default())
+			slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+
+			handler := RequestLoggerMiddleware(RequestLoggerConfig{
+				MaxBodyBytes:   64,
+				RedactedFields: DefaultRedactedFields,
+			})(tt.handler)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/user", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			tt.doCheck(t, logs.String(), w)
+		})
+	}
+}
+
+func TestMaxBodyMiddleware(t *testing.T) {
+	handler := MaxBodyMiddleware(16)(response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		var req CreateOrLoginUserRequest
+		if err := response.DecodeJSON(r, &req); err != nil {
+			return err
+		}
+		return nil
+	}))
+
+	tests := []struct {
+		name          string
+		body          string
+		contentLength int64
+		status        int
+	}{
+		{name: "Small body", body: `{}`, contentLength: 2, status: http.StatusOK},
+		{name: "Declared large body", body: `{"Username":"FAGD"}`, contentLength: 19, status: http.StatusRequestEntityTooLarge},
+		{name: "Undeclared large body", body: `{"Username":"FAGD"}`, contentLength: -1, status: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/user", strings.NewReader(tt.body))
+			req.ContentLength = tt.contentLength
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("Expected %d but got (%d): %s\n", tt.status, w.Code, w.Body)
+			}
+		})
+	}
+}
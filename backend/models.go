@@ -1,33 +1,41 @@
 package main
 
-import (
-	"github.com/google/uuid"
-)
+import "github.com/ElrohirGT/RankPoll/api"
 
-type Rank struct {
-	Option   string
-	Position uint
-}
+// The models and the bodies of the API are shared with the Go client through the api package.
+type (
+	Option          = api.Option
+	Rank            = api.Rank
+	Vote            = api.Vote
+	ResultStatus    = api.ResultStatus
+	PollSummary     = api.PollSummary
+	WriteInSettings = api.WriteInSettings
+	Eligibility     = api.Eligibility
+	Quorum          = api.Quorum
+	RoundReason     = api.RoundReason
+	Round           = api.Round
+	Room[T any]     = api.Room[T]
 
-type Vote struct {
-	Username string
-	Ranking  []Rank
-}
+	CreateOrLoginUserRequest  = api.CreateOrLoginUserRequest
+	CreateOrLoginUserResponse = api.CreateOrLoginUserResponse
+	PollOptionRequest         = api.PollOptionRequest
+	CreatePollRequest         = api.CreatePollRequest
+	CreatePollResponse        = api.CreatePollResponse
+	VoteInPollRequest         = api.VoteInPollRequest
+	AddWriteInRequest         = api.AddWriteInRequest
+	AddWriteInResponse        = api.AddWriteInResponse
+	ModerateWriteInRequest    = api.ModerateWriteInRequest
+	UpdatePollRequest         = api.UpdatePollRequest
+)
 
-type PollSummary struct {
-	Winner          string
-	WinnerVoteCount uint
-	TotalVoteCount  uint
-	Rounds          []map[string]uint
-}
+const (
+	StatusDecided  = api.StatusDecided
+	StatusTied     = api.StatusTied
+	StatusNoVotes  = api.StatusNoVotes
+	StatusNoQuorum = api.StatusNoQuorum
 
-// Normally T will be time.Time but sometimes it needs to be something else.
-// For example an int64 for representing Unix time.
-type Room[T any] struct {
-	Id         uuid.UUID
-	Title      string
-	Options    []string
-	Votes      map[string]Vote
-	Summary    *PollSummary
-	ValidUntil T
-}
+	ReasonElected         = api.ReasonElected
+	ReasonLastRound       = api.ReasonLastRound
+	ReasonTied            = api.ReasonTied
+	ReasonNextPreferences = api.ReasonNextPreferences
+)
@@ -0,0 +1,446 @@
+package main
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ElrohirGT/RankPoll/metrics"
+	"github.com/ElrohirGT/RankPoll/response"
+	"github.com/google/uuid"
+)
+
+// apiOperation documents a route of MountHandlers on the OpenAPI document.
+type apiOperation struct {
+	Id      string
+	Summary string
+	// Zero value of the body the route decodes, nil if it has none.
+	Request any
+	// Zero value of the body the route answers with, nil if it has none.
+	Response any
+	// Media type of the response, JSON if empty.
+	MediaType string
+	Query     []apiParameter
+	// How the route uses the HTTP Basic credentials of the user.
+	Auth apiAuth
+	// Statuses of the errors the route answers with.
+	// Their body is an ErrorResponse, or either that or the given value.
+	Errors map[int]any
+}
+
+type apiParameter struct {
+	Name        string
+	Description string
+	Enum        []string
+}
+
+type apiAuth int
+
+const (
+	noAuth apiAuth = iota
+	// Authenticated users get more from the route, like the owner of the poll.
+	optionalAuth
+	requiredAuth
+)
+
+// apiRoute is a route mounted by MountHandlers.
+type apiRoute struct {
+	Pattern string
+	// Pattern of the operation that documents the route, the successor of deprecated routes.
+	Operation  string
+	Deprecated bool
+}
+
+var exportParameters = map[string]apiParameter{
+	"names": {Name: "names", Description: "Names the candidates by option id instead of label.", Enum: []string{"ids"}},
+	"data":  {Name: "data", Description: "Exports the tally of each round instead of the ballots.", Enum: []string{"rounds"}},
+}
+
+var apiOperations = map[string]apiOperation{
+	"POST /api/v1/users": {
+		Id:       "CreateOrLoginUser",
+		Summary:  "Registers the user if it doesn't exist, otherwise checks its password.",
+		Request:  CreateOrLoginUserRequest{},
+		Response: CreateOrLoginUserResponse{},
+		Errors:   map[int]any{http.StatusBadRequest: nil, http.StatusTooManyRequests: nil},
+	},
+	"POST /api/v1/polls": {
+		Id:       "CreatePoll",
+		Summary:  "Creates a poll that accepts votes for the polling duration.",
+		Request:  CreatePollRequest{},
+		Response: CreatePollResponse{},
+		Errors:   map[int]any{http.StatusBadRequest: nil, http.StatusTooManyRequests: nil},
+	},
+	"POST /api/v1/polls/import": {
+		Id:       "ImportPoll",
+		Summary:  "Creates a closed poll from ballots counted outside RankPoll.",
+		Request:  ImportPollRequest{},
+		Response: ImportPollResponse{},
+		Errors:   map[int]any{http.StatusBadRequest: ImportPollResponse{}, http.StatusTooManyRequests: nil},
+	},
+	"GET /api/v1/polls/{pollId}": {
+		Id:       "GetPollInfo",
+		Summary:  "Gets the poll, with its summary once it's closed.",
+		Response: Room[int64]{},
+		Errors:   map[int]any{http.StatusNotFound: nil},
+	},
+	"PATCH /api/v1/polls/{pollId}": {
+		Id:       "UpdatePoll",
+		Summary:  "Changes the title or the end of an open poll, only its owner can.",
+		Request:  UpdatePollRequest{},
+		Response: Room[int64]{},
+		Auth:     requiredAuth,
+		Errors:   map[int]any{http.StatusBadRequest: nil, http.StatusUnauthorized: nil, http.StatusForbidden: nil, http.StatusNotFound: nil},
+	},
+	"DELETE /api/v1/polls/{pollId}": {
+		Id:      "DeletePoll",
+		Summary: "Deletes the poll, only its owner can.",
+		Auth:    requiredAuth,
+		Errors:  map[int]any{http.StatusUnauthorized: nil, http.StatusForbidden: nil, http.StatusNotFound: nil},
+	},
+	"GET /api/v1/polls/{pollId}/events": {
+		Id:        "StreamPoll",
+		Summary:   "Streams the poll as Server-Sent Events, a poll event when connecting and each time it changes, until it closes or a deleted event.",
+		Response:  Room[int64]{},
+		MediaType: MediaEventStream,
+		Errors:    map[int]any{http.StatusNotFound: nil},
+	},
+	"POST /api/v1/polls/{pollId}/votes": {
+		Id:      "VoteInPoll",
+		Summary: "Casts the ballot of a voter, the allowed voters of the poll must authenticate.",
+		Request: VoteInPollRequest{},
+		Auth:    optionalAuth,
+		Errors:  map[int]any{http.StatusBadRequest: nil, http.StatusUnauthorized: nil, http.StatusForbidden: nil, http.StatusNotFound: nil, http.StatusTooManyRequests: nil},
+	},
+	"POST /api/v1/polls/{pollId}/options": {
+		Id:       "AddWriteIn",
+		Summary:  "Proposes a write-in option, the ones of the owner are approved right away.",
+		Request:  AddWriteInRequest{},
+		Response: AddWriteInResponse{},
+		Auth:     optionalAuth,
+		Errors:   map[int]any{http.StatusBadRequest: nil, http.StatusUnauthorized: nil, http.StatusForbidden: nil, http.StatusNotFound: nil},
+	},
+	"POST /api/v1/polls/{pollId}/options/{optionId}": {
+		Id:      "ModerateWriteIn",
+		Summary: "Approves or rejects a pending write-in, only the owner of the poll can.",
+		Request: ModerateWriteInRequest{},
+		Auth:    requiredAuth,
+		Errors:  map[int]any{http.StatusBadRequest: nil, http.StatusUnauthorized: nil, http.StatusForbidden: nil, http.StatusNotFound: nil},
+	},
+	"GET /api/v1/polls/{pollId}/export/blt": {
+		Id:        "ExportBLT",
+		Summary:   "Exports the ballots of a closed poll as a BLT file, without the voters.",
+		Response:  "",
+		MediaType: response.MediaText,
+		Query:     []apiParameter{exportParameters["names"]},
+		Errors:    map[int]any{http.StatusNotFound: nil, http.StatusConflict: nil},
+	},
+	"GET /api/v1/polls/{pollId}/export/csv": {
+		Id:        "ExportCSV",
+		Summary:   "Exports the ballots of a closed poll as CSV. Only its owner can export who cast each ballot, unless the poll has no owner.",
+		Response:  "",
+		MediaType: response.MediaCSV,
+		Query:     []apiParameter{exportParameters["data"]},
+		Auth:      optionalAuth,
+		Errors:    map[int]any{http.StatusUnauthorized: nil, http.StatusForbidden: nil, http.StatusNotFound: nil, http.StatusConflict: nil},
+	},
+	"GET /api/v1/polls/{pollId}/export/jsonl": {
+		Id:        "ExportJSONLines",
+		Summary:   "Exports the ballots of a closed poll as JSON Lines, one Vote or Round on each line. Only its owner can export who cast each ballot, unless the poll has no owner.",
+		Response:  Vote{},
+		MediaType: response.MediaJSONLines,
+		Query:     []apiParameter{exportParameters["data"]},
+		Auth:      optionalAuth,
+		Errors:    map[int]any{http.StatusUnauthorized: nil, http.StatusForbidden: nil, http.StatusNotFound: nil, http.StatusConflict: nil},
+	},
+	"GET /healthz": {
+		Id:       "Healthz",
+		Summary:  "Answers as long as the server is running.",
+		Response: HealthResponse{},
+	},
+	"GET /readyz": {
+		Id:       "Readyz",
+		Summary:  "Fails while the polls are loading or the server is shutting down.",
+		Response: HealthResponse{},
+		Errors:   map[int]any{http.StatusServiceUnavailable: nil},
+	},
+	"GET /version": {
+		Id:       "Version",
+		Summary:  "Reports the build info of the server.",
+		Response: VersionResponse{},
+	},
+	"GET /metrics": {
+		Id:        "Metrics",
+		Summary:   "Exposes the metrics of the server in the Prometheus text format.",
+		Response:  "",
+		MediaType: metrics.ContentType,
+	},
+	"GET /openapi.json": {
+		Id:       "OpenAPI",
+		Summary:  "Serves this OpenAPI document.",
+		Response: struct{}{},
+	},
+}
+
+// Values of the string types that are enums.
+var schemaEnums = map[reflect.Type][]string{
+	reflect.TypeFor[ResultStatus](): {string(StatusDecided), string(StatusTied), string(StatusNoVotes), string(StatusNoQuorum)},
+	reflect.TypeFor[RoundReason]():  {string(ReasonElected), string(ReasonLastRound), string(ReasonTied), string(ReasonNextPreferences)},
+	reflect.TypeFor[ImportFormat](): {string(ImportBLT), string(ImportCSV)},
+}
+
+// NewOpenAPI generates the OpenAPI document of the routes,
+// failing if one of them isn't documented on apiOperations or an operation isn't mounted.
+func NewOpenAPI(routes []apiRoute) ([]byte, error) {
+	for pattern := range apiOperations {
+		if !slices.Contains(routes, apiRoute{Pattern: pattern, Operation: pattern}) {
+			return nil, fmt.Errorf("the OpenAPI operation %s isn't mounted", pattern)
+		}
+	}
+
+	schemas := &schemaGenerator{
+		components: make(map[string]any),
+		types:      make(map[string]reflect.Type),
+	}
+	errorSchema := schemas.schema(reflect.TypeFor[response.ErrorResponse]())
+
+	paths := make(map[string]any)
+	for _, route := range routes {
+		op, found := apiOperations[route.Operation]
+		if !found {
+			return nil, fmt.Errorf("the route %s has no OpenAPI operation", route.Pattern)
+		}
+
+		method, path, _ := strings.Cut(route.Pattern, " ")
+		operation := map[string]any{
+			"operationId": op.Id,
+			"summary":     op.Summary,
+			"parameters":  parameters(path, op.Query),
+			"responses":   responses(schemas, op, errorSchema),
+		}
+		switch op.Auth {
+		case optionalAuth:
+			operation["security"] = []any{map[string]any{}, map[string]any{"basicAuth": []any{}}}
+		case requiredAuth:
+			operation["security"] = []any{map[string]any{"basicAuth": []any{}}}
+		}
+		if route.Deprecated {
+			operation["operationId"] = op.Id + "Unversioned"
+			operation["deprecated"] = true
+		}
+		if op.Request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  content(response.MediaJSON, schemas.schema(reflect.TypeOf(op.Request))),
+			}
+		}
+
+		pathItem, found := paths[path].(map[string]any)
+		if !found {
+			pathItem = make(map[string]any)
+			paths[path] = pathItem
+		}
+		pathItem[strings.ToLower(method)] = operation
+	}
+
+	return json.MarshalIndent(map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "RankPoll",
+			"version": "1",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]any{
+				"basicAuth": map[string]any{
+					"type":        "http",
+					"scheme":      "basic",
+					"description": "Username and password of a registered user.",
+				},
+			},
+		},
+	}, "", "  ")
+}
+
+// OpenAPIHandler serves the OpenAPI document.
+func OpenAPIHandler(document []byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(document)
+	})
+}
+
+// parameters documents the wildcards of the path, which are all ids, and the query of the route.
+func parameters(path string, query []apiParameter) []any {
+	params := make([]any, 0)
+	for segment := range strings.SplitSeq(path, "/") {
+		if name, isWildcard := strings.CutPrefix(segment, "{"); isWildcard {
+			params = append(params, map[string]any{
+				"name":     strings.TrimSuffix(name, "}"),
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "string", "format": "uuid"},
+			})
+		}
+	}
+
+	for _, param := range query {
+		params = append(params, map[string]any{
+			"name":        param.Name,
+			"in":          "query",
+			"description": param.Description,
+			"schema":      map[string]any{"type": "string", "enum": param.Enum},
+		})
+	}
+	return params
+}
+
+func responses(schemas *schemaGenerator, op apiOperation, errorSchema map[string]any) map[string]any {
+	ok := map[string]any{"description": http.StatusText(http.StatusOK)}
+	if op.Response != nil {
+		mediaType := op.MediaType
+		if mediaType == "" {
+			mediaType = response.MediaJSON
+		}
+		ok["content"] = content(mediaType, schemas.schema(reflect.TypeOf(op.Response)))
+	}
+
+	resps := map[string]any{
+		"200": ok,
+		"default": map[string]any{
+			"description": "Error",
+			"content":     content(response.MediaJSON, errorSchema),
+		},
+	}
+
+	statuses := make([]int, 0, len(op.Errors))
+	for status := range op.Errors {
+		statuses = append(statuses, status)
+	}
+	if op.Request != nil {
+		statuses = append(statuses, http.StatusBadRequest, http.StatusRequestEntityTooLarge)
+	}
+
+	for _, status := range statuses {
+		schema := errorSchema
+		if body := op.Errors[status]; body != nil {
+			schema = map[string]any{"oneOf": []any{errorSchema, schemas.schema(reflect.TypeOf(body))}}
+		}
+
+		resps[fmt.Sprint(status)] = map[string]any{
+			"description": http.StatusText(status),
+			"content":     content(response.MediaJSON, schema),
+		}
+	}
+	return resps
+}
+
+func content(mediaType string, schema map[string]any) map[string]any {
+	return map[string]any{mediaType: map[string]any{"schema": schema}}
+}
+
+// schemaGenerator derives the schemas of Go types the way encoding/json encodes them,
+// named structs and enums are added as components.
+type schemaGenerator struct {
+	components map[string]any
+	types      map[string]reflect.Type
+}
+
+var textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]any {
+	switch t {
+	case reflect.TypeFor[time.Time]():
+		return map[string]any{"type": "string", "format": "date-time"}
+	case reflect.TypeFor[uuid.UUID]():
+		return map[string]any{"type": "string", "format": "uuid"}
+	case reflect.TypeFor[time.Duration]():
+		return map[string]any{"type": "integer", "format": "int64", "description": "Nanoseconds."}
+	}
+
+	if enum, found := schemaEnums[t]; found {
+		return g.component(t, func() map[string]any {
+			return map[string]any{"type": "string", "enum": enum}
+		})
+	}
+
+	if t.Implements(textMarshalerType) {
+		return map[string]any{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+
+	case reflect.Pointer:
+		return nullable(g.schema(t.Elem()))
+	case reflect.Slice, reflect.Array:
+		return nullable(map[string]any{"type": "array", "items": g.schema(t.Elem())})
+	case reflect.Map:
+		return nullable(map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())})
+
+	case reflect.Struct:
+		object := func() map[string]any {
+			properties := make(map[string]any)
+			for i := range t.NumField() {
+				if field := t.Field(i); field.IsExported() {
+					properties[field.Name] = g.schema(field.Type)
+				}
+			}
+			return map[string]any{"type": "object", "properties": properties}
+		}
+		if t.Name() == "" {
+			return object()
+		}
+		return g.component(t, object)
+	}
+
+	return map[string]any{}
+}
+
+// component adds the schema of the named type to the components, referencing it.
+// Generic types are named without their type arguments.
+func (g *schemaGenerator) component(t reflect.Type, schema func() map[string]any) map[string]any {
+	name, _, _ := strings.Cut(t.Name(), "[")
+	ref := map[string]any{"$ref": "#/components/schemas/" + name}
+
+	if existing, found := g.types[name]; found {
+		if existing != t {
+			panic(fmt.Sprintf("the types %s and %s have the same OpenAPI schema name", existing, t))
+		}
+		return ref
+	}
+
+	// Registered before generating it so recursive types end.
+	g.types[name] = t
+	g.components[name] = schema()
+	return ref
+}
+
+// nullable marks the schema as one that may be null, references can't have siblings so they're wrapped.
+func nullable(schema map[string]any) map[string]any {
+	if _, isRef := schema["$ref"]; isRef {
+		return map[string]any{"allOf": []any{schema}, "nullable": true}
+	}
+
+	withNull := maps.Clone(schema)
+	withNull["nullable"] = true
+	return withNull
+}
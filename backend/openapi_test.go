@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+var updateOpenAPI = flag.Bool("update-openapi", false, "rewrite openapi.json with the generated document")
+
+// openapi.json is what other teams generate clients from,
+// so it must change along with the handler types.
+func TestOpenAPI(t *testing.T) {
+	router := http.NewServeMux()
+	MountHandlers(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Failed to get the document (%d): %s\n", w.Code, w.Body)
+	}
+	generated := append(w.Body.Bytes(), '\n')
+
+	if *updateOpenAPI {
+		if err := os.WriteFile("openapi.json", generated, 0o644); err != nil {
+			t.Fatalf("Failed to update openapi.json: %s\n", err)
+		}
+	}
+
+	committed, err := os.ReadFile("openapi.json")
+	if err != nil {
+		t.Fatalf("Failed to read openapi.json: %s\n", err)
+	}
+
+	if !bytes.Equal(committed, generated) {
+		t.Fatalf("openapi.json drifted from the handler types, run `go test -run TestOpenAPI -update-openapi` to regenerate it\n")
+	}
+}
+
+func TestNewOpenAPI(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes []apiRoute
+	}{
+		{
+			name:   "Undocumented route",
+			routes: []apiRoute{{Pattern: "GET /api/v1/undocumented", Operation: "GET /api/v1/undocumented"}},
+		},
+		{
+			name:   "Operation not mounted",
+			routes: []apiRoute{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewOpenAPI(tt.routes); err == nil {
+				t.Fatalf("Expected the document to fail\n")
+			}
+		})
+	}
+}
@@ -5,6 +5,11 @@
 buildGoModule {
   pname = "RankPoll";
   version = "1.0.0";
-  src = ./.;
+  src = ../.;
+  subPackages = ["backend"];
   vendorHash = "sha256-mGKxBRU5TPgdmiSx0DHEd0Ys8gsVD/YdBfbDdSVpC3U=";
+
+  postInstall = ''
+    mv $out/bin/backend $out/bin/RankPoll
+  '';
 }
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ElrohirGT/RankPoll/response"
+)
+
+// tokenBucket starts full and refills at the rate of its limiter.
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// rateLimiter keeps a token bucket for each key, like a client IP or a username.
+type rateLimiter struct {
+	limit     RateLimit
+	lock      sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	return &rateLimiter{
+		limit:   limit,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+func (l *rateLimiter) ratePerSecond() float64 {
+	return l.limit.PerMinute / 60
+}
+
+// Allow takes a token from the bucket of the key.
+// If it's empty it returns how long until a token is available.
+func (l *rateLimiter) Allow(key string, now time.Time) (bool, time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.sweep(now)
+
+	burst := float64(l.limit.Burst)
+	bucket, found := l.buckets[key]
+	if !found {
+		bucket = &tokenBucket{tokens: burst, updated: now}
+		l.buckets[key] = bucket
+	}
+
+	elapsed := now.Sub(bucket.updated).Seconds()
+	bucket.tokens = min(burst, bucket.tokens+elapsed*l.ratePerSecond())
+	bucket.updated = now
+
+	if bucket.tokens < 1 {
+		missing := (1 - bucket.tokens) / l.ratePerSecond()
+		return false, time.Duration(missing * float64(time.Second))
+	}
+
+	bucket.tokens--
+	return true, 0
+}
+
+// sweep forgets the buckets that are full again, at most once a minute.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	refillTime := time.Duration(float64(l.limit.Burst) / l.ratePerSecond() * float64(time.Second))
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.updated) >= refillTime {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+type rateLimiterKey struct{}
+
+// RateLimitRoute limits the requests each client IP makes to the route.
+// The limiter is also used by handlers to limit each username through limitByUsername.
+func RateLimitRoute(limit RateLimit, trustedProxies []netip.Prefix, next http.Handler) http.Handler {
+	limiter := newRateLimiter(limit)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := clientIP(r, trustedProxies)
+		if allowed, retryAfter := limiter.Allow("ip:"+ip, time.Now()); !allowed {
+			setRetryAfter(w, retryAfter)
+			response.WriteError(w, r, response.NewError(http.StatusTooManyRequests, response.CodeRateLimited, "Too many requests!", errors.New("too many requests from this address")))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), rateLimiterKey{}, limiter)))
+	})
+}
+
+// limitByUsername takes a token of the username from the limiter of the route, if it has one.
+func limitByUsername(w http.ResponseWriter, r *http.Request, username string) error {
+	limiter, found := r.Context().Value(rateLimiterKey{}).(*rateLimiter)
+	if !found || username == "" {
+		return nil
+	}
+
+	if allowed, retryAfter := limiter.Allow("user:"+username, time.Now()); !allowed {
+		setRetryAfter(w, retryAfter)
+		return response.NewError(http.StatusTooManyRequests, response.CodeRateLimited, "Too many requests!", errors.New("too many requests for this user"))
+	}
+	return nil
+}
+
+// setRetryAfter rounds up to whole seconds.
+func setRetryAfter(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := max(1, int(math.Ceil(retryAfter.Seconds())))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+}
+
+// clientIP returns the address of the client, following X-Forwarded-For only through trusted proxies.
+// The header is read from right to left so clients can't spoof it by sending their own.
+func clientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	isTrusted := func(ip string) bool {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return false
+		}
+		addr = addr.Unmap()
+		for _, prefix := range trustedProxies {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !isTrusted(host) {
+		return host
+	}
+
+	forwarded := make([]string, 0)
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for ip := range strings.SplitSeq(header, ",") {
+			forwarded = append(forwarded, strings.TrimSpace(ip))
+		}
+	}
+
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		if !isTrusted(forwarded[i]) {
+			return forwarded[i]
+		}
+		host = forwarded[i]
+	}
+	return host
+}
+
+// parseTrustedProxies parses IPs and CIDRs, a single IP is taken as a prefix of itself.
+func parseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
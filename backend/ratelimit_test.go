@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(RateLimit{PerMinute: 60, Burst: 2})
+	now := time.Now()
+
+	for i := range 2 {
+		if allowed, _ := limiter.Allow("ip:1.2.3.4", now); !allowed {
+			t.Fatalf("Expected request %d of the burst to be allowed\n", i+1)
+		}
+	}
+
+	allowed, retryAfter := limiter.Allow("ip:1.2.3.4", now)
+	if allowed || retryAfter != time.Second {
+		t.Fatalf("Expected to retry after 1s but got %t, %s\n", allowed, retryAfter)
+	}
+
+	if allowed, _ := limiter.Allow("ip:5.6.7.8", now); !allowed {
+		t.Fatalf("Expected other keys to have their own bucket\n")
+	}
+
+	if allowed, _ := limiter.Allow("ip:1.2.3.4", now.Add(time.Second)); !allowed {
+		t.Fatalf("Expected the bucket to refill after a second\n")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trustedProxies, err := parseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatalf("Failed to parse proxies: %s\n", err)
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		expectedIP   string
+	}{
+		{name: "Direct client", remoteAddr: "1.2.3.4:5000", expectedIP: "1.2.3.4"},
+		{name: "Untrusted proxy is ignored", remoteAddr: "1.2.3.4:5000", forwardedFor: "9.9.9.9", expectedIP: "1.2.3.4"},
+		{name: "Trusted proxy", remoteAddr: "10.0.0.2:5000", forwardedFor: "5.6.7.8", expectedIP: "5.6.7.8"},
+		{name: "Spoofed header behind proxies", remoteAddr: "192.168.1.1:5000", forwardedFor: "9.9.9.9, 5.6.7.8, 10.0.0.3", expectedIP: "5.6.7.8"},
+		{name: "Only proxies", remoteAddr: "10.0.0.2:5000", forwardedFor: "10.0.0.3", expectedIP: "10.0.0.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/user", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+
+			ip := clientIP(req, trustedProxies)
+			if ip != tt.expectedIP {
+				t.Fatalf("Expected %s but got %s\n", tt.expectedIP, ip)
+			}
+		})
+	}
+}
+
+func TestRateLimitRoute(t *testing.T) {
+	handler := RateLimitRoute(RateLimit{PerMinute: 1, Burst: 1}, nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	statuses := make([]int, 0)
+	for range 2 {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/user", nil))
+		statuses = append(statuses, w.Code)
+
+		if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "60" {
+			t.Fatalf("Expected to retry after 60 seconds but got `%s`\n", w.Header().Get("Retry-After"))
+		}
+	}
+
+	if statuses[0] != http.StatusOK || statuses[1] != http.StatusTooManyRequests {
+		t.Fatalf("Expected the second request to be limited but got %v\n", statuses)
+	}
+}
@@ -1,10 +1,150 @@
 package main
 
-import "net/http"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ElrohirGT/RankPoll/response"
+)
 
 func MountHandlers(router *http.ServeMux) {
-	router.HandleFunc("/api/user", CreateOrLoginUser)
-	router.HandleFunc("/api/poll", CreatePoll)
-	router.HandleFunc("GET /api/poll/{pollId}", GetPollInfo)
-	router.HandleFunc("/api/vote", VoteInPoll)
+	trustedProxies, _ := parseTrustedProxies(AppConfig.RateLimit.TrustedProxies)
+	routes := make([]apiRoute, 0)
+	limited := func(pattern string, h http.Handler) http.Handler {
+		if limit, found := AppConfig.RateLimit.Routes[routePath(pattern)]; found && AppConfig.RateLimit.Enabled {
+			return RateLimitRoute(limit, trustedProxies, h)
+		}
+		return h
+	}
+	handle := func(pattern string, handler response.HandlerFunc) http.Handler {
+		h := limited(pattern, response.Handle(handler))
+		router.Handle(pattern, InstrumentRoute(pattern, h))
+		routes = append(routes, apiRoute{Pattern: pattern, Operation: pattern})
+		return h
+	}
+	// Aliases share the rate limits of their successor but are counted on their own.
+	alias := func(pattern string, successor string, h http.Handler) {
+		router.Handle(pattern, InstrumentRoute(pattern, DeprecatedRoute(successor, h)))
+		method, _, _ := strings.Cut(pattern, " ")
+		routes = append(routes, apiRoute{Pattern: pattern, Operation: method + " " + successor, Deprecated: true})
+	}
+
+	createOrLoginUser := handle("POST /api/v1/users", CreateOrLoginUser)
+	createPoll := handle("POST /api/v1/polls", CreatePoll)
+	importPoll := handle("POST /api/v1/polls/import", ImportPoll)
+	getPollInfo := handle("GET /api/v1/polls/{pollId}", GetPollInfo)
+	handle("PATCH /api/v1/polls/{pollId}", UpdatePoll)
+	handle("DELETE /api/v1/polls/{pollId}", DeletePoll)
+	handle("GET /api/v1/polls/{pollId}/events", StreamPoll)
+	voteInPoll := handle("POST /api/v1/polls/{pollId}/votes", VoteInPoll)
+	addWriteIn := handle("POST /api/v1/polls/{pollId}/options", AddWriteIn)
+	moderateWriteIn := handle("POST /api/v1/polls/{pollId}/options/{optionId}", ModerateWriteIn)
+	exportBLT := handle("GET /api/v1/polls/{pollId}/export/blt", ExportBLT)
+	exportCSV := handle("GET /api/v1/polls/{pollId}/export/csv", ExportCSV)
+	exportJSONLines := handle("GET /api/v1/polls/{pollId}/export/jsonl", ExportJSONLines)
+
+	// Deprecated: Kept for clients of the unversioned API.
+	alias("POST /api/user", "/api/v1/users", createOrLoginUser)
+	alias("POST /api/poll", "/api/v1/polls", createPoll)
+	alias("POST /api/poll/import", "/api/v1/polls/import", importPoll)
+	alias("GET /api/poll/{pollId}", "/api/v1/polls/{pollId}", getPollInfo)
+	alias("POST /api/poll/{pollId}/options", "/api/v1/polls/{pollId}/options", addWriteIn)
+	alias("POST /api/poll/{pollId}/options/{optionId}", "/api/v1/polls/{pollId}/options/{optionId}", moderateWriteIn)
+	alias("GET /api/poll/{pollId}/export/blt", "/api/v1/polls/{pollId}/export/blt", exportBLT)
+	alias("GET /api/poll/{pollId}/export/csv", "/api/v1/polls/{pollId}/export/csv", exportCSV)
+	alias("GET /api/poll/{pollId}/export/jsonl", "/api/v1/polls/{pollId}/export/jsonl", exportJSONLines)
+	alias("POST /api/vote", "/api/v1/polls/{pollId}/votes", voteInPoll)
+
+	handle("GET /healthz", Healthz)
+	handle("GET /readyz", Readyz)
+	handle("GET /version", Version)
+	router.Handle("GET /metrics", limited("GET /metrics", Metrics.Handler()))
+	routes = append(routes, apiRoute{Pattern: "GET /metrics", Operation: "GET /metrics"})
+
+	// Undocumented routes are a bug, like the conflicting patterns ServeMux panics on.
+	routes = append(routes, apiRoute{Pattern: "GET /openapi.json", Operation: "GET /openapi.json"})
+	openAPI, err := NewOpenAPI(routes)
+	if err != nil {
+		panic(err)
+	}
+	router.Handle("GET /openapi.json", limited("GET /openapi.json", OpenAPIHandler(openAPI)))
+
+	var frontend http.Handler
+	if AppConfig.Frontend.Enabled {
+		frontend = FrontendHandler(AppConfig.Frontend)
+	}
+	router.Handle("/", FallbackHandler(router, frontend))
+}
+
+// isMountedPath checks if MountHandlers mounts a route on the path, whatever its method.
+// Every mounted route is documented, so the operations of the OpenAPI document list them all.
+func isMountedPath(path string) bool {
+	for pattern := range apiOperations {
+		if routePath(pattern) == path {
+			return true
+		}
+	}
+	return false
+}
+
+// FallbackHandler answers the requests no route matched.
+// If the path has routes for other methods it's a 405 with the Allow header,
+// otherwise the request goes to the frontend, or is a 404 if it's disabled.
+func FallbackHandler(router *http.ServeMux, frontend http.Handler) http.Handler {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		if allowed := allowedMethods(router, r); len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			return response.NewError(http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, "Method not allowed!", fmt.Errorf("the route doesn't accept %s requests", r.Method))
+		}
+
+		if frontend == nil {
+			return response.NewError(http.StatusNotFound, response.CodeRouteNotFound, "Route not found!", errors.New("no route matches the request"))
+		}
+		frontend.ServeHTTP(w, r)
+		return nil
+	})
+}
+
+// allowedMethods returns the methods the router has routes for on the path of the request.
+func allowedMethods(router *http.ServeMux, r *http.Request) []string {
+	allowed := make([]string, 0)
+	for _, method := range validMethods {
+		if method != http.MethodOptions && isKnownRoute(router, r, method) {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
+
+// DeprecatedRoute tells clients of the route to move to its successor,
+// a pattern whose wildcards are filled with the values of the request.
+func DeprecatedRoute(successor string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		if link, ok := expandPattern(successor, r); ok {
+			w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, link))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// expandPattern replaces the wildcards of the pattern with the path values of the request,
+// false if the request doesn't have one of them.
+func expandPattern(pattern string, r *http.Request) (string, bool) {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		name, isWildcard := strings.CutPrefix(segment, "{")
+		if !isWildcard {
+			continue
+		}
+
+		value := r.PathValue(strings.TrimSuffix(name, "}"))
+		if value == "" {
+			return "", false
+		}
+		segments[i] = value
+	}
+	return strings.Join(segments, "/"), true
 }
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMountHandlers(t *testing.T) {
+	router := http.NewServeMux()
+	MountHandlers(router)
+
+	serveAs := func(method string, path string, body any, username string, password string) *httptest.ResponseRecorder {
+		var reqBody bytes.Buffer
+		if body != nil {
+			_ = json.NewEncoder(&reqBody).Encode(body)
+		}
+
+		req := httptest.NewRequest(method, path, &reqBody)
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	serve := func(method string, path string, body any) *httptest.ResponseRecorder {
+		return serveAs(method, path, body, "", "")
+	}
+
+	t.Run("Method restrictions", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			method string
+			path   string
+			status int
+			allow  string
+		}{
+			{name: "Get on create poll", method: http.MethodGet, path: "/api/v1/polls", status: http.StatusMethodNotAllowed, allow: "POST"},
+			{name: "Get on deprecated vote", method: http.MethodGet, path: "/api/vote", status: http.StatusMethodNotAllowed, allow: "POST"},
+			{name: "Post on poll", method: http.MethodPost, path: "/api/v1/polls/" + uuid.NewString(), status: http.StatusMethodNotAllowed, allow: "GET, HEAD, PATCH, DELETE"},
+			{name: "Post on health", method: http.MethodPost, path: "/healthz", status: http.StatusMethodNotAllowed, allow: "GET, HEAD"},
+			{name: "Patch on unknown poll", method: http.MethodPatch, path: "/api/v1/polls/" + uuid.NewString(), status: http.StatusNotFound},
+			{name: "Unknown API route", method: http.MethodGet, path: "/api/v1/unknown", status: http.StatusNotFound},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := serve(tt.method, tt.path, struct{}{})
+
+				if w.Code != tt.status {
+					t.Fatalf("Expected %d but got (%d): %s\n", tt.status, w.Code, w.Body)
+				}
+
+				if allow := w.Header().Get("Allow"); allow != tt.allow {
+					t.Fatalf("Expected Allow `%s` but got `%s`\n", tt.allow, allow)
+				}
+			})
+		}
+	})
+
+	t.Run("Poll lifecycle", func(t *testing.T) {
+		defer CleanGlobalState()
+
+		w := serve(http.MethodPost, "/api/v1/users", CreateOrLoginUserRequest{Username: "Owner", Password: "12345"})
+		if w.Code != http.StatusOK {
+			t.Fatalf("Failed to create user (%d): %s\n", w.Code, w.Body)
+		}
+
+		w = serve(http.MethodPost, "/api/v1/polls", CreatePollRequest{
+			Title:           "Lenguaje",
+			Username:        "Owner",
+			PollingDuration: 5 * time.Second,
+			PollOptions:     []string{"Español", "Alemán"},
+		})
+		if w.Code != http.StatusOK {
+			t.Fatalf("Failed to create poll (%d): %s\n", w.Code, w.Body)
+		}
+
+		var pollResponse CreatePollResponse
+		if err := json.NewDecoder(w.Body).Decode(&pollResponse); err != nil {
+			t.Fatalf("Failed to decode response: %s\n", err)
+		}
+		pollPath := "/api/v1/polls/" + pollResponse.PollId.String()
+
+		title := "Idioma"
+		w = serveAs(http.MethodPatch, pollPath, UpdatePollRequest{Title: &title}, "Owner", "12345")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Failed to update poll (%d): %s\n", w.Code, w.Body)
+		}
+
+		var roomInfo Room[int64]
+		if err := json.NewDecoder(w.Body).Decode(&roomInfo); err != nil {
+			t.Fatalf("Failed to decode response: %s\n", err)
+		}
+		if roomInfo.Title != title {
+			t.Fatalf("Expected the title to be %s but got %s\n", title, roomInfo.Title)
+		}
+
+		w = serve(http.MethodPost, pollPath+"/votes", VoteInPollRequest{
+			Username: "Tyron",
+			Options:  map[string]uint{"Español": 1, "Alemán": 2},
+		})
+		if w.Code != http.StatusOK {
+			t.Fatalf("Failed to vote (%d): %s\n", w.Code, w.Body)
+		}
+
+		w = serve(http.MethodPost, pollPath+"/votes", VoteInPollRequest{
+			Username: "Yuniqua",
+			PollId:   uuid.New(),
+			Options:  map[string]uint{"Español": 1, "Alemán": 2},
+		})
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("Voting with another poll in the body should fail (%d): %s\n", w.Code, w.Body)
+		}
+
+		w = serve(http.MethodGet, "/api/poll/"+pollResponse.PollId.String(), nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Failed to get poll through the deprecated route (%d): %s\n", w.Code, w.Body)
+		}
+		if w.Header().Get("Deprecation") != "true" {
+			t.Fatalf("The deprecated route isn't marked as such!\n")
+		}
+		if link, expected := w.Header().Get("Link"), "<"+pollPath+`>; rel="successor-version"`; link != expected {
+			t.Fatalf("Expected Link `%s` but got `%s`\n", expected, link)
+		}
+
+		w = serve(http.MethodDelete, pollPath, nil)
+		if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+			t.Fatalf("Deleting the poll should ask for credentials (%d): %s\n", w.Code, w.Body)
+		}
+
+		w = serveAs(http.MethodDelete, pollPath, nil, "Owner", "wrong")
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("Wrong credentials should be rejected (%d): %s\n", w.Code, w.Body)
+		}
+
+		w = serve(http.MethodPost, "/api/v1/users", CreateOrLoginUserRequest{Username: "Tyron", Password: "54321"})
+		if w.Code != http.StatusOK {
+			t.Fatalf("Failed to create user (%d): %s\n", w.Code, w.Body)
+		}
+
+		w = serveAs(http.MethodDelete, pollPath, nil, "Tyron", "54321")
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("Only the owner should delete the poll (%d): %s\n", w.Code, w.Body)
+		}
+
+		w = serveAs(http.MethodDelete, pollPath, nil, "Owner", "12345")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Failed to delete poll (%d): %s\n", w.Code, w.Body)
+		}
+
+		w = serve(http.MethodGet, pollPath, nil)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("The poll wasn't deleted (%d): %s\n", w.Code, w.Body)
+		}
+	})
+
+	t.Run("Failed credentials lock the account", func(t *testing.T) {
+		defer CleanGlobalState()
+
+		w := serve(http.MethodPost, "/api/v1/users", CreateOrLoginUserRequest{Username: "Locked", Password: "12345"})
+		if w.Code != http.StatusOK {
+			t.Fatalf("Failed to create user (%d): %s\n", w.Code, w.Body)
+		}
+
+		w = serve(http.MethodPost, "/api/v1/polls", CreatePollRequest{
+			Title:           "Lenguaje",
+			Username:        "Locked",
+			PollingDuration: 5 * time.Second,
+			PollOptions:     []string{"Español", "Alemán"},
+		})
+		if w.Code != http.StatusOK {
+			t.Fatalf("Failed to create poll (%d): %s\n", w.Code, w.Body)
+		}
+
+		var pollResponse CreatePollResponse
+		if err := json.NewDecoder(w.Body).Decode(&pollResponse); err != nil {
+			t.Fatalf("Failed to decode response: %s\n", err)
+		}
+		pollPath := "/api/v1/polls/" + pollResponse.PollId.String()
+
+		for range AppConfig.RateLimit.MaxLoginFailures {
+			w = serveAs(http.MethodDelete, pollPath, nil, "Locked", "wrong")
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("Wrong credentials should be rejected (%d): %s\n", w.Code, w.Body)
+			}
+		}
+
+		w = serveAs(http.MethodDelete, pollPath, nil, "Locked", "12345")
+		if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+			t.Fatalf("Expected the account to be locked but got (%d): %s\n", w.Code, w.Body)
+		}
+
+		w = serve(http.MethodPost, "/api/v1/users", CreateOrLoginUserRequest{Username: "Locked", Password: "12345"})
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("The lock should also apply to logins (%d): %s\n", w.Code, w.Body)
+		}
+
+		w = serve(http.MethodGet, pollPath, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("The poll shouldn't be deleted (%d): %s\n", w.Code, w.Body)
+		}
+	})
+}
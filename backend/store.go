@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SnapshotFile is the name of the snapshot inside AppConfig.StoragePath.
+const SnapshotFile = "polls.json"
+
+// snapshot is the part of GlobalState that outlives the server,
+// login failures are forgotten when it restarts.
+type snapshot struct {
+	Users map[string]string
+	Rooms map[string]Room[time.Time]
+}
+
+// SaveSnapshot writes the users and polls to the directory,
+// the previous snapshot is only replaced once the new one is complete.
+func SaveSnapshot(dir string) error {
+	GlobalState.Lock.RLock()
+	data, err := json.Marshal(snapshot{Users: GlobalState.Users, Rooms: GlobalState.Rooms})
+	GlobalState.Lock.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(dir, SnapshotFile+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), filepath.Join(dir, SnapshotFile))
+}
+
+// LoadSnapshot adds the users and polls of the snapshot in the directory to GlobalState,
+// a directory without a snapshot has nothing to load.
+func LoadSnapshot(dir string) error {
+	data, err := os.ReadFile(filepath.Join(dir, SnapshotFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var s snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	GlobalState.Lock.Lock()
+	defer GlobalState.Lock.Unlock()
+
+	maps.Copy(GlobalState.Users, s.Users)
+	maps.Copy(GlobalState.Rooms, s.Rooms)
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSnapshot(t *testing.T) {
+	tests := []struct {
+		name    string
+		doCheck func(t *testing.T, dir string)
+	}{
+		{
+			name: "Restores users and polls",
+			doCheck: func(t *testing.T, dir string) {
+				pollId := uuid.New()
+				option := Option{Id: uuid.New(), Label: "Español"}
+				GlobalState.Users["Owner"] = "12345"
+				GlobalState.LoginFailures["Owner"] = LoginFailures{Count: 2}
+				GlobalState.Rooms[pollId.String()] = Room[time.Time]{
+					Id:          pollId,
+					Title:       "Lenguaje",
+					Owner:       "Owner",
+					Options:     []Option{option},
+					Eligibility: Eligibility{InvitationCodes: map[string]bool{"secret": false}},
+					Votes: map[string]Vote{
+						"Tyron": {Username: "Tyron", Ranking: []Rank{{OptionId: option.Id, Position: 1}}},
+					},
+					ValidUntil: time.Now().Add(time.Minute),
+				}
+
+				if err := SaveSnapshot(dir); err != nil {
+					t.Fatalf("Failed to save snapshot: %s\n", err)
+				}
+				CleanGlobalState()
+
+				if err := LoadSnapshot(dir); err != nil {
+					t.Fatalf("Failed to load snapshot: %s\n", err)
+				}
+
+				if GlobalState.Users["Owner"] != "12345" {
+					t.Fatalf("The user wasn't restored! %#v\n", GlobalState.Users)
+				}
+				if len(GlobalState.LoginFailures) != 0 {
+					t.Fatalf("Login failures shouldn't be saved! %#v\n", GlobalState.LoginFailures)
+				}
+
+				room, found := GlobalState.Rooms[pollId.String()]
+				if !found || room.Title != "Lenguaje" || room.Votes["Tyron"].Ranking[0].OptionId != option.Id {
+					t.Fatalf("The poll wasn't restored! %#v\n", room)
+				}
+				if _, found := room.Eligibility.InvitationCodes["secret"]; !found {
+					t.Fatalf("The invitation codes weren't restored! %#v\n", room.Eligibility)
+				}
+			},
+		},
+		{
+			name: "Nothing to load",
+			doCheck: func(t *testing.T, dir string) {
+				if err := LoadSnapshot(dir); err != nil {
+					t.Fatalf("Expected an empty directory to load nothing but got: %s\n", err)
+				}
+
+				if len(GlobalState.Rooms) != 0 {
+					t.Fatalf("Expected no polls but got %d\n", len(GlobalState.Rooms))
+				}
+			},
+		},
+		{
+			name: "Corrupted snapshot",
+			doCheck: func(t *testing.T, dir string) {
+				err := os.WriteFile(filepath.Join(dir, SnapshotFile), []byte(`{"Rooms":`), 0o600)
+				if err != nil {
+					t.Fatalf("Failed to write snapshot: %s\n", err)
+				}
+
+				if err := LoadSnapshot(dir); err == nil {
+					t.Fatalf("Expected the corrupted snapshot to be rejected\n")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			CleanGlobalState()
+			defer CleanGlobalState()
+			tt.doCheck(t, t.TempDir())
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/ElrohirGT/RankPoll/response"
+	"github.com/google/uuid"
+)
+
+func computeSummary(ctx context.Context, room *Room[time.Time]) {
+	logger := response.Logger(ctx).With("poll_id", room.Id)
+	start := time.Now()
+	summary := &PollSummary{
+		Rounds:          make([]Round, 0),
+		BallotCount:     uint(len(room.Votes)),
+		RequiredBallots: room.Quorum.RequiredBallots(room.Eligibility.EligibleCount()),
+	}
+	room.Summary = summary
+
+	summary.QuorumMet = summary.BallotCount >= summary.RequiredBallots
+	if summary.BallotCount == 0 {
+		logger.Info("No votes were cast")
+		summary.Status = StatusNoVotes
+		return
+	}
+
+	if !summary.QuorumMet {
+		logger.Info("No quorum", "ballots", summary.BallotCount, "required_ballots", summary.RequiredBallots)
+		summary.Status = StatusNoQuorum
+		return
+	}
+
+	tallyBucklin(ctx, room, summary)
+	summaryDuration.Observe(time.Since(start).Seconds())
+
+	if summary.Status == StatusDecided {
+		winner, _ := room.FindOption(summary.WinnerId)
+		summary.Winner = winner.Label
+		logger.Info("Poll decided", "winner_id", summary.WinnerId)
+	} else {
+		logger.Info("Poll tied", "tied_options", summary.TiedOptions)
+	}
+}
+
+func tallyBucklin(ctx context.Context, room *Room[time.Time], summary *PollSummary) {
+	for roundIdx := range len(room.Options) {
+		round := uint(roundIdx + 1)
+		// Options nobody ranked yet are still on the tally, with no votes.
+		roundTally := make(map[uuid.UUID]uint, len(room.Options))
+		for _, opt := range room.Options {
+			roundTally[opt.Id] = 0
+		}
+		transfers := make(map[uuid.UUID]uint)
+
+		var exhausted uint = 0
+		for _, vote := range room.Votes {
+			counted := false
+			for _, r := range vote.Ranking {
+				if r.Position > round {
+					continue
+				}
+
+				roundTally[r.OptionId] += 1
+				if r.Position == round && round > 1 {
+					transfers[r.OptionId] += 1
+				}
+				counted = true
+			}
+
+			if !counted {
+				exhausted++
+			}
+		}
+
+		var totalCount uint = 0
+		for _, voteCount := range roundTally {
+			totalCount += voteCount
+		}
+		leaders, maxCount := optionsWithMost(roundTally)
+
+		transcript := Round{
+			Number:           round,
+			Tally:            tallyByKey(roundTally),
+			Threshold:        totalCount/uint(len(room.Options)) + 1,
+			Leaders:          leaders,
+			Transfers:        tallyByKey(transfers),
+			ExhaustedBallots: exhausted,
+		}
+
+		isUnique := len(leaders) == 1
+		isLastRound := round == uint(len(room.Options))
+		response.Logger(ctx).Debug("Bucklin round", "poll_id", room.Id, "round", round, "is_unique", isUnique, "max_count", maxCount, "threshold", transcript.Threshold)
+		switch {
+		case isUnique && maxCount >= transcript.Threshold:
+			transcript.Reason = ReasonElected
+		case isLastRound && isUnique:
+			transcript.Reason = ReasonLastRound
+		case isLastRound:
+			transcript.Reason = ReasonTied
+		default:
+			transcript.Reason = ReasonNextPreferences
+		}
+		summary.Rounds = append(summary.Rounds, transcript)
+
+		if transcript.Reason == ReasonNextPreferences {
+			continue
+		}
+
+		summary.WinnerVoteCount = maxCount
+		summary.TotalVoteCount = totalCount
+		if transcript.Reason == ReasonTied {
+			summary.Status = StatusTied
+			summary.TiedOptions = leaders
+		} else {
+			summary.Status = StatusDecided
+			summary.WinnerId = leaders[0]
+		}
+		break
+	}
+}
+
+// optionsWithMost returns the sorted ids of the options with most votes.
+func optionsWithMost(tally map[uuid.UUID]uint) ([]uuid.UUID, uint) {
+	best := make([]uuid.UUID, 0)
+	var bestCount uint = 0
+	for optId, count := range tally {
+		if len(best) == 0 || count > bestCount {
+			best = []uuid.UUID{optId}
+			bestCount = count
+		} else if count == bestCount {
+			best = append(best, optId)
+		}
+	}
+
+	slices.SortFunc(best, func(a, b uuid.UUID) int {
+		return bytes.Compare(a[:], b[:])
+	})
+	return best, bestCount
+}
+
+func tallyByKey(tally map[uuid.UUID]uint) map[string]uint {
+	byKey := make(map[string]uint, len(tally))
+	for optId, count := range tally {
+		byKey[optId.String()] = count
+	}
+	return byKey
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestComputeSummary(t *testing.T) {
+	teacher := Option{Id: uuid.New(), Label: "Teacher"}
+	doctor := Option{Id: uuid.New(), Label: "Doctor"}
+	options := []Option{teacher, doctor}
+
+	plumber := Option{Id: uuid.New(), Label: "Plumber"}
+
+	ballot := func(username string, preferences ...Option) Vote {
+		vote := Vote{Username: username}
+		for i, opt := range preferences {
+			vote.Ranking = append(vote.Ranking, Rank{OptionId: opt.Id, Position: uint(i + 1)})
+		}
+		return vote
+	}
+
+	sortedIds := func(opts ...Option) []uuid.UUID {
+		ids := make([]uuid.UUID, 0, len(opts))
+		for _, opt := range opts {
+			ids = append(ids, opt.Id)
+		}
+		slices.SortFunc(ids, func(a, b uuid.UUID) int {
+			return bytes.Compare(a[:], b[:])
+		})
+		return ids
+	}
+
+	tests := []struct {
+		name   string
+		room   Room[time.Time]
+		status ResultStatus
+		winner string
+		// Only the reasons, leaders and transfers are checked.
+		rounds []Round
+	}{
+		{
+			name: "Decided",
+			room: Room[time.Time]{Options: options, Votes: map[string]Vote{
+				"Tyron":   ballot("Tyron", doctor, teacher),
+				"Yuniqua": ballot("Yuniqua", doctor, teacher),
+				"Tasha":   ballot("Tasha", teacher, doctor),
+			}},
+			status: StatusDecided,
+			winner: "Doctor",
+		},
+		{
+			name: "Tied",
+			room: Room[time.Time]{Options: options, Votes: map[string]Vote{
+				"Tyron": ballot("Tyron", doctor, teacher),
+				"Tasha": ballot("Tasha", teacher, doctor),
+			}},
+			status: StatusTied,
+		},
+		{
+			name:   "No votes",
+			room:   Room[time.Time]{Options: options, Votes: map[string]Vote{}},
+			status: StatusNoVotes,
+		},
+		{
+			name: "No quorum",
+			room: Room[time.Time]{Options: options, Quorum: Quorum{MinBallots: 2}, Votes: map[string]Vote{
+				"Tyron": ballot("Tyron", doctor, teacher),
+			}},
+			status: StatusNoQuorum,
+		},
+		{
+			name: "Next preferences are transferred",
+			room: Room[time.Time]{
+				Options: []Option{teacher, doctor, plumber},
+				Votes: map[string]Vote{
+					"Tyron":   ballot("Tyron", teacher, doctor, plumber),
+					"Yuniqua": ballot("Yuniqua", doctor, plumber, teacher),
+					"Pablo":   ballot("Pablo", plumber, doctor, teacher),
+				},
+			},
+			status: StatusDecided,
+			winner: "Doctor",
+			rounds: []Round{
+				{Number: 1, Threshold: 2, Reason: ReasonNextPreferences, Leaders: sortedIds(teacher, doctor, plumber)},
+				{Number: 2, Threshold: 3, Reason: ReasonElected, Leaders: sortedIds(doctor), Transfers: map[string]uint{doctor.Id.String(): 2, plumber.Id.String(): 1}},
+			},
+		},
+		{
+			name: "Unranked options are tallied",
+			room: Room[time.Time]{
+				Options: []Option{teacher, doctor, plumber},
+				Votes: map[string]Vote{
+					"Tyron": ballot("Tyron", doctor),
+					"Pablo": ballot("Pablo", doctor),
+				},
+			},
+			status: StatusDecided,
+			winner: "Doctor",
+			rounds: []Round{
+				{Number: 1, Threshold: 1, Reason: ReasonElected, Leaders: sortedIds(doctor)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			computeSummary(t.Context(), &tt.room)
+
+			summary := tt.room.Summary
+			if summary.Status != tt.status {
+				t.Fatalf("Expected status %s but got %s!\n%#v", tt.status, summary.Status, *summary)
+			}
+
+			if summary.Winner != tt.winner {
+				t.Fatalf("Expected winner %q but got %q!\n%#v", tt.winner, summary.Winner, *summary)
+			}
+
+			if tt.status == StatusTied && len(summary.TiedOptions) != 2 {
+				t.Fatalf("Tie should be between both options!\n%#v", *summary)
+			}
+
+			if tt.rounds == nil {
+				return
+			}
+
+			if len(summary.Rounds) != len(tt.rounds) {
+				t.Fatalf("Expected %d rounds but got %d!\n%#v", len(tt.rounds), len(summary.Rounds), summary.Rounds)
+			}
+
+			for i, expected := range tt.rounds {
+				round := summary.Rounds[i]
+				if round.Number != expected.Number || round.Threshold != expected.Threshold || round.Reason != expected.Reason {
+					t.Fatalf("Round %d doesn't match!\nExpected: %#v\nGot: %#v", i+1, expected, round)
+				}
+
+				if !slices.Equal(round.Leaders, expected.Leaders) {
+					t.Fatalf("Round %d leaders don't match!\nExpected: %v\nGot: %v", i+1, expected.Leaders, round.Leaders)
+				}
+
+				if len(round.Tally) != len(tt.room.Options) {
+					t.Fatalf("Round %d should tally every option!\n%#v", i+1, round.Tally)
+				}
+
+				if len(round.Transfers) != len(expected.Transfers) {
+					t.Fatalf("Round %d transfers don't match!\nExpected: %#v\nGot: %#v", i+1, expected.Transfers, round.Transfers)
+				}
+				for optId, count := range expected.Transfers {
+					if round.Transfers[optId] != count {
+						t.Fatalf("Round %d transfers don't match!\nExpected: %#v\nGot: %#v", i+1, expected.Transfers, round.Transfers)
+					}
+				}
+			}
+		})
+	}
+}
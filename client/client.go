@@ -0,0 +1,304 @@
+// Package client is a typed Go client of the RankPoll API.
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ElrohirGT/RankPoll/api"
+	"github.com/ElrohirGT/RankPoll/response"
+	"github.com/google/uuid"
+)
+
+const DefaultRetries = 3
+const DefaultRetryDelay = 200 * time.Millisecond
+
+// Error is an error response of the API, branch on its Code.
+type Error struct {
+	StatusCode int
+	response.ErrorResponse
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rankpoll: %d %s", e.StatusCode, e.ErrorResponse)
+}
+
+type Client struct {
+	baseUrl    string
+	httpClient *http.Client
+	retries    int
+	retryDelay time.Duration
+	username   string
+	password   string
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sets the client used to send the requests, http.DefaultClient by default.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries sets how many times a request is retried when the server answers with 5xx,
+// waiting delay before the first retry and doubling it each time.
+// Only idempotent requests are retried, so a vote is never cast twice.
+func WithRetries(retries int, delay time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.retryDelay = delay
+	}
+}
+
+// WithBasicAuth sends the credentials of the user with every request,
+// the owner of a poll and its allowed voters need them.
+func WithBasicAuth(username string, password string) Option {
+	return func(c *Client) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// New creates a client of the API served at baseUrl, for example https://rankpoll.example.com.
+func New(baseUrl string, options ...Option) *Client {
+	c := &Client{
+		baseUrl:    strings.TrimSuffix(baseUrl, "/"),
+		httpClient: http.DefaultClient,
+		retries:    DefaultRetries,
+		retryDelay: DefaultRetryDelay,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// CreateOrLoginUser registers the user if it doesn't exist, otherwise checks its password.
+func (c *Client) CreateOrLoginUser(ctx context.Context, req api.CreateOrLoginUserRequest) (api.CreateOrLoginUserResponse, error) {
+	var resp api.CreateOrLoginUserResponse
+	err := c.do(ctx, http.MethodPost, "/api/v1/users", req, &resp)
+	return resp, err
+}
+
+func (c *Client) CreatePoll(ctx context.Context, req api.CreatePollRequest) (api.CreatePollResponse, error) {
+	var resp api.CreatePollResponse
+	err := c.do(ctx, http.MethodPost, "/api/v1/polls", req, &resp)
+	return resp, err
+}
+
+// VoteInPoll casts the ballot in the poll of req.PollId.
+func (c *Client) VoteInPoll(ctx context.Context, req api.VoteInPollRequest) error {
+	return c.do(ctx, http.MethodPost, pollPath(req.PollId)+"/votes", req, nil)
+}
+
+// GetPollInfo gets the poll, it has its summary once it's closed.
+// ValidUntil is in Unix milliseconds.
+func (c *Client) GetPollInfo(ctx context.Context, pollId uuid.UUID) (api.Room[int64], error) {
+	var resp api.Room[int64]
+	err := c.do(ctx, http.MethodGet, pollPath(pollId), nil, &resp)
+	return resp, err
+}
+
+// StreamPoll yields the poll when connecting and each time it changes,
+// ending after the poll closes, when it's deleted or when the context is canceled.
+//
+//	for poll, err := range c.StreamPoll(ctx, pollId) {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(len(poll.Votes))
+//	}
+func (c *Client) StreamPoll(ctx context.Context, pollId uuid.UUID) iter.Seq2[api.Room[int64], error] {
+	return func(yield func(api.Room[int64], error) bool) {
+		resp, err := c.send(ctx, http.MethodGet, pollPath(pollId)+"/events", nil, "text/event-stream")
+		if err != nil {
+			yield(api.Room[int64]{}, err)
+			return
+		}
+		defer resp.Body.Close()
+
+		if err := checkStatus(resp); err != nil {
+			yield(api.Room[int64]{}, err)
+			return
+		}
+
+		for event, err := range readEvents(resp.Body) {
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				yield(api.Room[int64]{}, err)
+				return
+			}
+
+			if event.name != "poll" {
+				continue
+			}
+
+			var poll api.Room[int64]
+			if err := json.Unmarshal([]byte(event.data), &poll); err != nil {
+				yield(api.Room[int64]{}, err)
+				return
+			}
+			if !yield(poll, nil) {
+				return
+			}
+		}
+	}
+}
+
+func pollPath(pollId uuid.UUID) string {
+	return "/api/v1/polls/" + pollId.String()
+}
+
+// do sends the body as JSON and decodes the response into out, if it isn't nil.
+func (c *Client) do(ctx context.Context, method string, path string, body any, out any) error {
+	resp, err := c.send(ctx, method, path, body, "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// send makes the request, retrying it while the server answers with 5xx if it's idempotent.
+// The last response is returned even if it's an error.
+func (c *Client) send(ctx context.Context, method string, path string, body any, accept string) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	delay := c.retryDelay
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, c.baseUrl+path, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", accept)
+		if c.username != "" {
+			req.SetBasicAuth(c.username, c.password)
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode < http.StatusInternalServerError || attempt >= c.retries || !isIdempotent(method) {
+			return resp, nil
+		}
+
+		wait := retryAfter(resp, delay)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// retryAfter prefers the seconds the server asks to wait over the delay.
+func retryAfter(resp *http.Response, delay time.Duration) time.Duration {
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return delay
+}
+
+// checkStatus converts error responses into an *Error.
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+
+	respErr := &Error{StatusCode: resp.StatusCode}
+	if err := json.NewDecoder(resp.Body).Decode(&respErr.ErrorResponse); err != nil {
+		respErr.Msg = http.StatusText(resp.StatusCode)
+	}
+	return respErr
+}
+
+type event struct {
+	name string
+	data string
+}
+
+// readEvents parses a stream of Server-Sent Events, comments and ids are ignored.
+func readEvents(r io.Reader) iter.Seq2[event, error] {
+	return func(yield func(event, error) bool) {
+		reader := bufio.NewReader(r)
+		var current event
+		var data []string
+
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				if err != io.EOF {
+					yield(event{}, err)
+				}
+				return
+			}
+
+			line = strings.TrimRight(line, "\r\n")
+			if line == "" {
+				if len(data) > 0 {
+					current.data = strings.Join(data, "\n")
+					if current.name == "" {
+						current.name = "message"
+					}
+					if !yield(current, nil) {
+						return
+					}
+				}
+				current, data = event{}, nil
+				continue
+			}
+
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				current.name = value
+			case "data":
+				data = append(data, value)
+			}
+		}
+	}
+}
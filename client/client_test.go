@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ElrohirGT/RankPoll/api"
+	"github.com/ElrohirGT/RankPoll/response"
+	"github.com/google/uuid"
+)
+
+func TestRetries(t *testing.T) {
+	pollId := uuid.New()
+
+	tests := []struct {
+		name     string
+		failures int
+		retries  int
+		status   int
+		attempts int
+		post     bool
+	}{
+		{name: "Succeeds after retrying", failures: 2, retries: 2, attempts: 3},
+		{name: "Gives up after the retries", failures: 3, retries: 2, status: http.StatusServiceUnavailable, attempts: 3},
+		{name: "Doesn't retry client errors", failures: 0, retries: 2, status: http.StatusNotFound, attempts: 1},
+		{name: "Doesn't retry posts", failures: 1, retries: 2, status: http.StatusServiceUnavailable, attempts: 1, post: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				if attempts <= tt.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					_ = json.NewEncoder(w).Encode(response.ErrorResponse{Code: response.CodeNotReady, Msg: "Server not ready!"})
+					return
+				}
+
+				if tt.status == http.StatusNotFound {
+					w.WriteHeader(http.StatusNotFound)
+					_ = json.NewEncoder(w).Encode(response.ErrorResponse{Code: response.CodePollNotFound, Msg: "Poll not found!"})
+					return
+				}
+				_ = json.NewEncoder(w).Encode(api.Room[int64]{Id: pollId, Title: "Lenguaje"})
+			}))
+			defer server.Close()
+
+			c := New(server.URL, WithRetries(tt.retries, time.Millisecond))
+			var poll api.Room[int64]
+			var err error
+			if tt.post {
+				_, err = c.CreatePoll(context.Background(), api.CreatePollRequest{Title: "Lenguaje"})
+			} else {
+				poll, err = c.GetPollInfo(context.Background(), pollId)
+			}
+
+			if attempts != tt.attempts {
+				t.Fatalf("Expected %d attempts but got %d\n", tt.attempts, attempts)
+			}
+
+			if tt.status == 0 {
+				if err != nil {
+					t.Fatalf("Failed to get poll: %s\n", err)
+				}
+				if poll.Id != pollId {
+					t.Fatalf("Ids don't match! %s != %s\n", poll.Id, pollId)
+				}
+				return
+			}
+
+			var respErr *Error
+			if !errors.As(err, &respErr) || respErr.StatusCode != tt.status {
+				t.Fatalf("Expected an error with status %d but got: %v\n", tt.status, err)
+			}
+		})
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "Tyron" || password != "12345" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(response.ErrorResponse{Code: response.CodeInvalidCredentials, Msg: "Invalid credentials!"})
+		}
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithBasicAuth("Tyron", "12345"))
+	err := c.VoteInPoll(context.Background(), api.VoteInPollRequest{Username: "Tyron", PollId: uuid.New()})
+	if err != nil {
+		t.Fatalf("Expected the credentials to be sent but got: %s\n", err)
+	}
+}
+
+func TestStreamPoll(t *testing.T) {
+	pollId := uuid.New()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/polls/"+pollId.String()+"/events" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for votes := range 3 {
+			data, _ := json.Marshal(api.Room[int64]{Id: pollId, Votes: make(map[string]api.Vote, votes)})
+			fmt.Fprintf(w, ": keep-alive\n\nevent: poll\ndata: %s\n\n", data)
+		}
+		fmt.Fprintf(w, "event: deleted\ndata: {}\n\n")
+	}))
+	defer server.Close()
+
+	c := New(server.URL)
+	count := 0
+	for poll, err := range c.StreamPoll(context.Background(), pollId) {
+		if err != nil {
+			t.Fatalf("Failed to stream poll: %s\n", err)
+		}
+		if poll.Id != pollId {
+			t.Fatalf("Ids don't match! %s != %s\n", poll.Id, pollId)
+		}
+		count++
+	}
+
+	if count != 3 {
+		t.Fatalf("Expected 3 polls but got %d\n", count)
+	}
+}
@@ -7,11 +7,6 @@ import Json.Encode as E
 import Types
 
 
-basePath : String
-basePath =
-    "http://127.0.0.1:8080"
-
-
 type alias LoginOrCreateUserRequest =
     Types.User
 
@@ -33,10 +28,10 @@ loginOrCreateUserResponseDecoder =
     D.map LoginOrCreateUserResponse (D.field "Msg" D.string)
 
 
-loginOrCreateUser : (Result Http.Error LoginOrCreateUserResponse -> msg) -> LoginOrCreateUserRequest -> Cmd msg
-loginOrCreateUser toMsg req =
+loginOrCreateUser : String -> (Result Http.Error LoginOrCreateUserResponse -> msg) -> LoginOrCreateUserRequest -> Cmd msg
+loginOrCreateUser basePath toMsg req =
     Http.post
-        { url = String.concat [ basePath, "/api/user" ]
+        { url = String.concat [ basePath, "/api/v1/users" ]
         , body = loginOrCreateUserRequestEncoder req |> Http.jsonBody
         , expect = Http.expectJson toMsg loginOrCreateUserResponseDecoder
         }
@@ -72,10 +67,10 @@ createPollResponseDecoder =
         (D.field "PollId" D.string)
 
 
-createPoll : (Result Http.Error CreatePollResponse -> msg) -> CreatePollRequest a -> Cmd msg
-createPoll toMsg req =
+createPoll : String -> (Result Http.Error CreatePollResponse -> msg) -> CreatePollRequest a -> Cmd msg
+createPoll basePath toMsg req =
     Http.post
-        { url = String.concat [ basePath, "/api/poll" ]
+        { url = String.concat [ basePath, "/api/v1/polls" ]
         , body = createPollRequestEncoder req |> Http.jsonBody
         , expect = Http.expectJson toMsg createPollResponseDecoder
         }
@@ -90,9 +85,9 @@ getPollResponseDecoder =
     Types.roomDecoder
 
 
-getPoll : (Result Http.Error GetPollResponse -> msg) -> String -> Cmd msg
-getPoll toMsg pollId =
+getPoll : String -> (Result Http.Error GetPollResponse -> msg) -> String -> Cmd msg
+getPoll basePath toMsg pollId =
     Http.get
-        { url = String.concat [ basePath, "/api/poll/", pollId ]
+        { url = String.concat [ basePath, "/api/v1/polls/", pollId ]
         , expect = Http.expectJson toMsg getPollResponseDecoder
         }
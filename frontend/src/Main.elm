@@ -42,13 +42,14 @@ type Pages
 
 type alias Model =
     { key : Nav.Key
+    , apiBaseUrl : String
     , page : Pages
     }
 
 
 init : Types.Flags -> Url.Url -> Nav.Key -> ( Model, Cmd Msg )
 init flags url key =
-    redirectToPage key url
+    redirectToPage flags.apiBaseUrl key url
 
 
 
@@ -64,8 +65,8 @@ type Msg
     | ViewPollViewMsg ViewPollPage.Msg
 
 
-redirectToPage : Nav.Key -> Url.Url -> ( Model, Cmd Msg )
-redirectToPage key url =
+redirectToPage : String -> Nav.Key -> Url.Url -> ( Model, Cmd Msg )
+redirectToPage apiBaseUrl key url =
     let
         newPage =
             Router.fromUrl url
@@ -76,9 +77,9 @@ redirectToPage key url =
                 ( innerModel, innerCmd ) =
                     key
                         |> Router.createNavigator
-                        |> LoginPage.init
+                        |> LoginPage.init apiBaseUrl
             in
-            ( Model key (LoginView innerModel)
+            ( Model key apiBaseUrl (LoginView innerModel)
             , Cmd.map LoginViewMsg innerCmd
             )
 
@@ -87,9 +88,9 @@ redirectToPage key url =
                 ( innerModel, innerCmd ) =
                     key
                         |> Router.createNavigator
-                        |> CreatePollPage.init
+                        |> CreatePollPage.init apiBaseUrl
             in
-            ( Model key (CreatePollView innerModel)
+            ( Model key apiBaseUrl (CreatePollView innerModel)
             , Cmd.map CreatePollViewMsg innerCmd
             )
 
@@ -100,7 +101,7 @@ redirectToPage key url =
                         |> Router.createNavigator
                         |> NotFoundPage.init
             in
-            ( Model key (NotFoundView innerModel)
+            ( Model key apiBaseUrl (NotFoundView innerModel)
             , Cmd.map NotFoundViewMsg innerCmd
             )
 
@@ -109,9 +110,9 @@ redirectToPage key url =
                 ( innerModel, innerCmd ) =
                     key
                         |> Router.createNavigator
-                        |> ViewPollPage.init id
+                        |> ViewPollPage.init apiBaseUrl id
             in
-            ( Model key (ViewPollView innerModel)
+            ( Model key apiBaseUrl (ViewPollView innerModel)
             , Cmd.map ViewPollViewMsg innerCmd
             )
 
@@ -142,7 +143,7 @@ update msg model =
                     ( model, Nav.load href )
 
         UrlChanged url ->
-            redirectToPage model.key url
+            redirectToPage model.apiBaseUrl model.key url
 
         LoginViewMsg innerMsg ->
             case model.page of
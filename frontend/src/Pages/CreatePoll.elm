@@ -13,17 +13,19 @@ type alias Model =
     { title : String
     , options : List String
     , durationInMinutes : Int
+    , apiBaseUrl : String
     , navigator : Router.Navigator Msg
     , newOption : String
     , error : Maybe String
     }
 
 
-init : Router.Navigator Msg -> ( Model, Cmd Msg )
-init navigator =
+init : String -> Router.Navigator Msg -> ( Model, Cmd Msg )
+init apiBaseUrl navigator =
     ( { title = ""
       , options = []
       , durationInMinutes = 0
+      , apiBaseUrl = apiBaseUrl
       , navigator = navigator
       , newOption = ""
       , error = Nothing
@@ -70,7 +72,7 @@ update msg model =
             )
 
         CreatePoll ->
-            ( { model | error = Nothing }, Api.createPoll PollCreated model )
+            ( { model | error = Nothing }, Api.createPoll model.apiBaseUrl PollCreated model )
 
         PollCreated res ->
             case res of
@@ -12,17 +12,19 @@ import Types
 
 type alias Model =
     { user : Types.User
+    , apiBaseUrl : String
     , navigator : Router.Navigator Msg
     , error : Maybe String
     }
 
 
-init : Router.Navigator Msg -> ( Model, Cmd Msg )
-init navigator =
+init : String -> Router.Navigator Msg -> ( Model, Cmd Msg )
+init apiBaseUrl navigator =
     ( { user =
             { username = ""
             , password = ""
             }
+      , apiBaseUrl = apiBaseUrl
       , navigator = navigator
       , error = Nothing
       }
@@ -61,7 +63,7 @@ update msg model =
             ( { model | user = newUser }, Cmd.none )
 
         ClickedLogin ->
-            ( model, Api.loginOrCreateUser LoginCompleted model.user )
+            ( model, Api.loginOrCreateUser model.apiBaseUrl LoginCompleted model.user )
 
         LoginCompleted result ->
             case result of
@@ -19,6 +19,7 @@ type State
 
 type alias Model =
     { pollId : String
+    , apiBaseUrl : String
     , navigator : Router.Navigator Msg
     , error : Maybe String
     , currentTime : Time.Posix
@@ -26,9 +27,10 @@ type alias Model =
     }
 
 
-init : String -> Router.Navigator Msg -> ( Model, Cmd Msg )
-init pollId navigator =
+init : String -> String -> Router.Navigator Msg -> ( Model, Cmd Msg )
+init apiBaseUrl pollId navigator =
     ( { pollId = pollId
+      , apiBaseUrl = apiBaseUrl
       , navigator = navigator
       , error = Nothing
       , state = Loading
@@ -36,7 +38,7 @@ init pollId navigator =
       }
     , Cmd.batch
         [ Task.perform TickClock Time.now
-        , Api.getPoll GotPoll pollId
+        , Api.getPoll apiBaseUrl GotPoll pollId
         ]
     )
 
@@ -138,10 +140,10 @@ displayOptions remainingMs room =
         [ text "You can no longer vote on this poll!" ]
 
 
-displayOption : String -> Html msg
+displayOption : Types.PollOption -> Html msg
 displayOption opt =
     div []
-        [ text opt
+        [ text opt.label
         , button [] [ text "Up" ]
         , button [] [ text "Down" ]
         ]
@@ -7,6 +7,9 @@ import Time
 
 type alias Flags =
     { user : Maybe User
+
+    -- Where the API is, empty when it's on the same origin as the frontend.
+    , apiBaseUrl : String
     }
 
 
@@ -16,8 +19,25 @@ type alias User =
     }
 
 
+type alias PollOption =
+    { id : String
+    , label : String
+    , description : String
+    , link : String
+    }
+
+
+pollOptionDecoder : D.Decoder PollOption
+pollOptionDecoder =
+    D.map4 PollOption
+        (D.field "Id" D.string)
+        (D.field "Label" D.string)
+        (D.field "Description" D.string)
+        (D.field "Link" D.string)
+
+
 type alias Rank =
-    { option : String
+    { optionId : String
     , position : Int
     }
 
@@ -25,7 +45,7 @@ type alias Rank =
 rankDecoder : D.Decoder Rank
 rankDecoder =
     D.map2 Rank
-        (D.field "Option" D.string)
+        (D.field "OptionId" D.string)
         (D.field "Position" D.int)
 
 
@@ -44,7 +64,7 @@ voteDecoder =
 
 type alias Room =
     { title : String
-    , options : List String
+    , options : List PollOption
     , votes : Dict String Vote
     , validUntil : Time.Posix
     , summary : Maybe PollSummary
@@ -60,7 +80,7 @@ roomDecoder : D.Decoder Room
 roomDecoder =
     D.map5 Room
         (D.field "Title" D.string)
-        (D.field "Options" (D.list D.string))
+        (D.field "Options" (D.list pollOptionDecoder))
         (D.field "Votes" (D.dict voteDecoder))
         (D.field "ValidUntil" posixTimeDecoder)
         (D.field "Summary" (D.maybe pollSummaryDecoder))
@@ -80,4 +100,4 @@ pollSummaryDecoder =
         (D.field "Winner" D.string)
         (D.field "WinnerVoteCount" D.int)
         (D.field "TotalVoteCount" D.int)
-        (D.field "Rounds" (D.list <| D.dict <| D.int))
+        (D.field "Rounds" (D.list <| D.field "Tally" <| D.dict <| D.int))
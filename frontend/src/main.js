@@ -9,7 +9,13 @@ try {
 	console.error("Failed to parse user from local storage!", e);
 }
 
+// Injected by the backend when it serves the frontend, dev builds call the local backend instead.
+const apiBaseUrl =
+	window.RANKPOLL_CONFIG?.ApiBaseUrl ??
+	import.meta.env.VITE_API_BASE_URL ??
+	"http://127.0.0.1:8080";
+
 Elm.Main.init({
 	node: document.getElementById("app"),
-	flags: { user },
+	flags: { user, apiBaseUrl },
 });
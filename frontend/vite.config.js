@@ -3,4 +3,9 @@ import elmPlugin from "vite-plugin-elm";
 
 export default defineConfig({
 	plugins: [elmPlugin()],
+	build: {
+		// Embedded into the backend binary.
+		outDir: "../backend/web/dist",
+		emptyOutDir: true,
+	},
 });
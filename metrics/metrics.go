@@ -0,0 +1,286 @@
+// Package metrics implements the counters, gauges and histograms the server exposes,
+// written in the Prometheus text exposition format.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are the upper bounds of the histogram buckets in seconds, the same Prometheus clients use.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type collector interface {
+	write(buf *bytes.Buffer)
+}
+
+// Registry holds the metrics that are exposed together.
+type Registry struct {
+	lock       sync.Mutex
+	collectors []collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: make([]collector, 0)}
+}
+
+func (r *Registry) register(c collector) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteTo writes every metric in the order they were created.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.lock.Lock()
+	collectors := slices.Clone(r.collectors)
+	r.lock.Unlock()
+
+	var buf bytes.Buffer
+	for _, c := range collectors {
+		c.write(&buf)
+	}
+	return buf.WriteTo(w)
+}
+
+// Handler serves the metrics to the Prometheus scraper.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_, _ = r.WriteTo(w)
+	})
+}
+
+// desc is what every metric has in common.
+type desc struct {
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+}
+
+func (d desc) writeHeader(buf *bytes.Buffer) {
+	fmt.Fprintf(buf, "# HELP %s %s\n", d.name, escapeHelp(d.help))
+	fmt.Fprintf(buf, "# TYPE %s %s\n", d.name, d.kind)
+}
+
+// seriesKey identifies the series of the label values, panicking if they don't match the label names.
+func (d desc) seriesKey(labelValues []string) string {
+	if len(labelValues) != len(d.labelNames) {
+		panic(fmt.Sprintf("metric %s expects %d label values but got %d", d.name, len(d.labelNames), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+// writeSample writes a line of the metric, le is the bound of histogram buckets and empty otherwise.
+func (d desc) writeSample(buf *bytes.Buffer, suffix string, labelValues []string, value float64, le string) {
+	buf.WriteString(d.name)
+	buf.WriteString(suffix)
+
+	labels := make([]string, 0, len(d.labelNames)+1)
+	for i, name := range d.labelNames {
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, name, escapeLabelValue(labelValues[i])))
+	}
+	if le != "" {
+		labels = append(labels, fmt.Sprintf(`le="%s"`, le))
+	}
+	if len(labels) > 0 {
+		fmt.Fprintf(buf, "{%s}", strings.Join(labels, ","))
+	}
+
+	fmt.Fprintf(buf, " %s\n", formatValue(value))
+}
+
+// Counter is a value that only goes up, partitioned by its labels.
+type Counter struct {
+	desc
+	lock   sync.Mutex
+	series map[string]*counterSeries
+}
+
+type counterSeries struct {
+	labelValues []string
+	value       float64
+}
+
+func (r *Registry) NewCounter(name string, help string, labelNames ...string) *Counter {
+	c := &Counter{
+		desc:   desc{name: name, help: help, kind: "counter", labelNames: labelNames},
+		series: make(map[string]*counterSeries),
+	}
+	r.register(c)
+	return c
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increases the counter, negative values are ignored.
+func (c *Counter) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		return
+	}
+
+	key := c.seriesKey(labelValues)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	s, found := c.series[key]
+	if !found {
+		s = &counterSeries{labelValues: slices.Clone(labelValues)}
+		c.series[key] = s
+	}
+	s.value += value
+}
+
+func (c *Counter) write(buf *bytes.Buffer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.writeHeader(buf)
+	if len(c.labelNames) == 0 && len(c.series) == 0 {
+		c.writeSample(buf, "", nil, 0, "")
+		return
+	}
+
+	for _, key := range sortedKeys(c.series) {
+		s := c.series[key]
+		c.writeSample(buf, "", s.labelValues, s.value, "")
+	}
+}
+
+// GaugeFunc is a value that can go up and down, computed each time it's scraped.
+type GaugeFunc struct {
+	desc
+	value func() float64
+}
+
+func (r *Registry) NewGaugeFunc(name string, help string, value func() float64) *GaugeFunc {
+	g := &GaugeFunc{
+		desc:  desc{name: name, help: help, kind: "gauge"},
+		value: value,
+	}
+	r.register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(buf *bytes.Buffer) {
+	g.writeHeader(buf)
+	g.writeSample(buf, "", nil, g.value(), "")
+}
+
+// Histogram counts observations in buckets, partitioned by its labels.
+type Histogram struct {
+	desc
+	buckets []float64
+	lock    sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labelValues []string
+	// Observations in each bucket, not cumulative.
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram creates a histogram with the upper bounds of the buckets,
+// the +Inf bucket is always added.
+func (r *Registry) NewHistogram(name string, help string, buckets []float64, labelNames ...string) *Histogram {
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+
+	h := &Histogram{
+		desc:    desc{name: name, help: help, kind: "histogram", labelNames: labelNames},
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+	}
+	r.register(h)
+	return h
+}
+
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	key := h.seriesKey(labelValues)
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	s, found := h.series[key]
+	if !found {
+		s = &histogramSeries{
+			labelValues: slices.Clone(labelValues),
+			counts:      make([]uint64, len(h.buckets)),
+		}
+		h.series[key] = s
+	}
+
+	if i, _ := slices.BinarySearch(h.buckets, value); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += value
+}
+
+func (h *Histogram) write(buf *bytes.Buffer) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.writeHeader(buf)
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			h.writeSample(buf, "_bucket", s.labelValues, float64(cumulative), formatValue(bound))
+		}
+		h.writeSample(buf, "_bucket", s.labelValues, float64(s.count), "+Inf")
+		h.writeSample(buf, "_sum", s.labelValues, s.sum, "")
+		h.writeSample(buf, "_count", s.labelValues, float64(s.count), "")
+	}
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeHelp(help string) string {
+	return helpReplacer.Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
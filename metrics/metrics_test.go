@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	tests := []struct {
+		name     string
+		record   func(r *Registry)
+		expected string
+	}{
+		{
+			name: "Counter without observations",
+			record: func(r *Registry) {
+				r.NewCounter("logins_total", "Logins.")
+			},
+			expected: "# HELP logins_total Logins.\n" +
+				"# TYPE logins_total counter\n" +
+				"logins_total 0\n",
+		},
+		{
+			name: "Counter with labels",
+			record: func(r *Registry) {
+				c := r.NewCounter("requests_total", "Requests\nhandled.", "route", "code")
+				c.Inc("/api/poll", "200")
+				c.Inc("/api/poll", "200")
+				c.Add(3, `/api/"quoted"`, "404")
+			},
+			expected: "# HELP requests_total Requests\\nhandled.\n" +
+				"# TYPE requests_total counter\n" +
+				`requests_total{route="/api/\"quoted\"",code="404"} 3` + "\n" +
+				`requests_total{route="/api/poll",code="200"} 2` + "\n",
+		},
+		{
+			name: "Gauge",
+			record: func(r *Registry) {
+				r.NewGaugeFunc("active_polls", "Active polls.", func() float64 { return 4 })
+			},
+			expected: "# HELP active_polls Active polls.\n" +
+				"# TYPE active_polls gauge\n" +
+				"active_polls 4\n",
+		},
+		{
+			name: "Histogram",
+			record: func(r *Registry) {
+				h := r.NewHistogram("duration_seconds", "Duration.", []float64{1, 0.5}, "method")
+				h.Observe(0.5, "BUCKLIN")
+				h.Observe(0.75, "BUCKLIN")
+				h.Observe(2, "BUCKLIN")
+			},
+			expected: "# HELP duration_seconds Duration.\n" +
+				"# TYPE duration_seconds histogram\n" +
+				`duration_seconds_bucket{method="BUCKLIN",le="0.5"} 1` + "\n" +
+				`duration_seconds_bucket{method="BUCKLIN",le="1"} 2` + "\n" +
+				`duration_seconds_bucket{method="BUCKLIN",le="+Inf"} 3` + "\n" +
+				`duration_seconds_sum{method="BUCKLIN"} 3.25` + "\n" +
+				`duration_seconds_count{method="BUCKLIN"} 3` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewRegistry()
+			tt.record(registry)
+
+			var out strings.Builder
+			if _, err := registry.WriteTo(&out); err != nil {
+				t.Fatalf("Failed to write metrics: %s\n", err)
+			}
+
+			if out.String() != tt.expected {
+				t.Fatalf("Expected:\n%s\nBut got:\n%s\n", tt.expected, out.String())
+			}
+		})
+	}
+}
@@ -1,13 +1,22 @@
 package response
 
 import (
-	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 )
 
 type ErrorResponse struct {
+	Code   ErrorCode
 	Msg    string
 	Reason string
+	// Path of the request field that caused the error, if any.
+	// For example: Options.Teacher
+	Field string
+}
+
+func (e ErrorResponse) String() string {
+	return fmt.Sprintf("%s: %s %s", e.Code, e.Msg, e.Reason)
 }
 
 type responseBuilder struct {
@@ -28,22 +37,44 @@ func (r responseBuilder) SetBody(body any) responseBuilder {
 	}
 }
 
-func (r responseBuilder) SetError(msg string, reason error) responseBuilder {
-	return responseBuilder{
-		status: r.status,
-		body:   ErrorResponse{Msg: msg, Reason: reason.Error()},
+// SendAs writes the response as the given media type,
+// ignoring what the client accepts.
+func (r responseBuilder) SendAs(w http.ResponseWriter, mediaType string) error {
+	if !canEncode(r.body, mediaType) {
+		return ErrNotAcceptable
 	}
-}
-
-func (r responseBuilder) SendAsJSON(w http.ResponseWriter) error {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(r.status)
 
-	bodyBytes, err := json.Marshal(r.body)
+	bodyBytes, err := encode(r.body, mediaType)
 	if err != nil {
 		return err
 	}
 
+	w.Header().Set("Content-Type", contentType(mediaType))
+	w.WriteHeader(r.status)
+
 	_, err = w.Write(bodyBytes)
 	return err
 }
+
+// Send writes the response as the type the request accepts.
+// If the body can't be sent as any of them it responds with 406 to safe requests,
+// other requests already changed something so they, like error responses, are sent as JSON anyway.
+func (r responseBuilder) Send(w http.ResponseWriter, req *http.Request) error {
+	w.Header().Add("Vary", "Accept")
+
+	accept := strings.Join(req.Header.Values("Accept"), ",")
+	mediaType, found := Negotiate(accept, r.body)
+	if found {
+		return r.SendAs(w, mediaType)
+	}
+
+	isSafe := req.Method == http.MethodGet || req.Method == http.MethodHead
+	if r.status >= http.StatusBadRequest || !isSafe {
+		return r.SendAs(w, MediaJSON)
+	}
+
+	notAcceptable := NewError(http.StatusNotAcceptable, CodeNotAcceptable, "Not acceptable!", fmt.Errorf("can't send the response as %s", accept))
+	return NewResponseBuilder(notAcceptable.Status).
+		SetBody(notAcceptable.Response()).
+		SendAs(w, MediaJSON)
+}
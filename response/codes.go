@@ -0,0 +1,39 @@
+package response
+
+// ErrorCode is a stable identifier of an error that clients can branch on,
+// unlike Msg and Reason which may change.
+type ErrorCode string
+
+const (
+	CodeInvalidBody        ErrorCode = "INVALID_BODY"
+	CodeBodyTooLarge       ErrorCode = "BODY_TOO_LARGE"
+	CodeInvalidCredentials ErrorCode = "INVALID_CREDENTIALS"
+	CodePollNotFound       ErrorCode = "POLL_NOT_FOUND"
+	CodeOptionNotFound     ErrorCode = "OPTION_NOT_FOUND"
+	CodeInvalidOptionCount ErrorCode = "INVALID_OPTION_COUNT"
+	CodeInvalidOption      ErrorCode = "INVALID_OPTION"
+	// A poll setting like the quorum is not valid.
+	CodeInvalidSettings ErrorCode = "INVALID_SETTINGS"
+	CodeAlreadyVoted    ErrorCode = "ALREADY_VOTED"
+	CodePollClosed      ErrorCode = "POLL_CLOSED"
+	// The action needs the poll to be closed.
+	CodePollOpen          ErrorCode = "POLL_OPEN"
+	CodeNotEligible       ErrorCode = "NOT_ELIGIBLE"
+	CodeUnknownOption     ErrorCode = "UNKNOWN_OPTION"
+	CodeIncompleteRanking ErrorCode = "INCOMPLETE_RANKING"
+	CodeInvalidRank       ErrorCode = "INVALID_RANK"
+	CodeWriteInsDisabled  ErrorCode = "WRITE_INS_DISABLED"
+	CodeWriteInLimit      ErrorCode = "WRITE_IN_LIMIT"
+	CodeNotOwner          ErrorCode = "NOT_OWNER"
+	CodeInvalidImport     ErrorCode = "INVALID_IMPORT"
+	CodeNotAcceptable     ErrorCode = "NOT_ACCEPTABLE"
+	CodeRouteNotFound     ErrorCode = "ROUTE_NOT_FOUND"
+	CodeMethodNotAllowed  ErrorCode = "METHOD_NOT_ALLOWED"
+	CodeOriginNotAllowed  ErrorCode = "ORIGIN_NOT_ALLOWED"
+	CodeRateLimited       ErrorCode = "RATE_LIMITED"
+	// Too many failed logins, the account can't log in for a while.
+	CodeAccountLocked ErrorCode = "ACCOUNT_LOCKED"
+	CodeInternal      ErrorCode = "INTERNAL_ERROR"
+	// The server is starting or shutting down.
+	CodeNotReady ErrorCode = "NOT_READY"
+)
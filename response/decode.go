@@ -0,0 +1,37 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DecodeJSON decodes the body of the request into v, rejecting unknown fields and trailing data.
+// Bodies over the limit set by http.MaxBytesReader are rejected with 413.
+func DecodeJSON(r *http.Request, v any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(v)
+	if err == nil {
+		if _, err = decoder.Token(); err == io.EOF {
+			return nil
+		} else if err == nil {
+			err = errors.New("unexpected data after the JSON object")
+		}
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return NewError(http.StatusRequestEntityTooLarge, CodeBodyTooLarge, "Body too large!", fmt.Errorf("the body can't be larger than %d bytes", maxBytesErr.Limit))
+	}
+
+	invalidBody := NewError(http.StatusBadRequest, CodeInvalidBody, "Invalid body!", err)
+	if field, found := strings.CutPrefix(err.Error(), "json: unknown field "); found {
+		return invalidBody.WithField(strings.Trim(field, `"`))
+	}
+	return invalidBody
+}
@@ -0,0 +1,52 @@
+package response
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	type request struct {
+		Username string
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		limit  int64
+		status int
+		field  string
+	}{
+		{name: "Valid", body: `{"Username":"FAGD"}`, limit: 100},
+		{name: "Unknown field", body: `{"Username":"FAGD","Pasword":"1234"}`, limit: 100, status: http.StatusBadRequest, field: "Pasword"},
+		{name: "Trailing data", body: `{"Username":"FAGD"} {}`, limit: 100, status: http.StatusBadRequest},
+		{name: "Too large", body: `{"Username":"FAGD"}`, limit: 5, status: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			r.Body = http.MaxBytesReader(httptest.NewRecorder(), r.Body, tt.limit)
+
+			var req request
+			err := DecodeJSON(r, &req)
+			if tt.status == 0 {
+				if err != nil || req.Username != "FAGD" {
+					t.Fatalf("Expected the body to be decoded but got: %v\n", err)
+				}
+				return
+			}
+
+			var respErr *Error
+			if !errors.As(err, &respErr) {
+				t.Fatalf("Expected an *Error but got: %v\n", err)
+			}
+			if respErr.Status != tt.status || respErr.Field != tt.field {
+				t.Fatalf("Expected %d on `%s` but got %d on `%s`\n", tt.status, tt.field, respErr.Status, respErr.Field)
+			}
+		})
+	}
+}
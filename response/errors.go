@@ -0,0 +1,130 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// Error is an error that knows the response it should be sent as.
+type Error struct {
+	Status int
+	Code   ErrorCode
+	Msg    string
+	// Can be nil.
+	Reason error
+	// Path of the request field that caused the error, if any.
+	Field string
+}
+
+func NewError(status int, code ErrorCode, msg string, reason error) *Error {
+	return &Error{Status: status, Code: code, Msg: msg, Reason: reason}
+}
+
+func (e *Error) WithField(path string) *Error {
+	withField := *e
+	withField.Field = path
+	return &withField
+}
+
+func (e *Error) Error() string {
+	if e.Reason == nil {
+		return fmt.Sprintf("%s: %s", e.Code, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s %s", e.Code, e.Msg, e.Reason)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Reason
+}
+
+// Response is the body the error is sent as.
+// If the reason is a JSON type error and no field was set, the field that failed is used.
+func (e *Error) Response() ErrorResponse {
+	errResponse := ErrorResponse{Code: e.Code, Msg: e.Msg, Field: e.Field}
+	if e.Reason != nil {
+		errResponse.Reason = e.Reason.Error()
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errResponse.Field == "" && errors.As(e.Reason, &typeErr) {
+		errResponse.Field = typeErr.Field
+	}
+
+	return errResponse
+}
+
+// HandlerFunc is an http.HandlerFunc that returns its errors instead of writing them.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// Handle converts the HandlerFunc into an http.Handler that writes the errors it returns.
+// Errors that aren't an *Error and panics are sent as 500.
+func Handle(h HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tracked := &trackingWriter{ResponseWriter: w}
+
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				Logger(r.Context()).Error("Recovered from panic", "method", r.Method, "path", r.URL.Path, "panic", recovered, "stack", string(debug.Stack()))
+				WriteError(tracked, r, fmt.Errorf("panic: %v", recovered))
+			}
+		}()
+
+		if err := h(tracked, r); err != nil {
+			WriteError(tracked, r, err)
+		}
+	})
+}
+
+// WriteError sends the error as a response.
+// If the response was already started the error is only logged.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	if tracked, ok := w.(*trackingWriter); ok && tracked.wroteHeader {
+		Logger(r.Context()).Error("Failed to write response", "method", r.Method, "path", r.URL.Path, "error", err)
+		return
+	}
+
+	var respErr *Error
+	if !errors.As(err, &respErr) {
+		Logger(r.Context()).Error("Internal error", "method", r.Method, "path", r.URL.Path, "error", err)
+		respErr = NewError(http.StatusInternalServerError, CodeInternal, "Internal server error!", errors.New("something went wrong"))
+	}
+
+	sendErr := NewResponseBuilder(respErr.Status).
+		SetBody(respErr.Response()).
+		Send(w, r)
+	if sendErr != nil {
+		Logger(r.Context()).Error("Failed to write error", "method", r.Method, "path", r.URL.Path, "error", sendErr)
+	}
+}
+
+// trackingWriter remembers if the response was already started.
+type trackingWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (t *trackingWriter) WriteHeader(status int) {
+	t.wroteHeader = true
+	t.ResponseWriter.WriteHeader(status)
+}
+
+func (t *trackingWriter) Write(b []byte) (int, error) {
+	t.wroteHeader = true
+	return t.ResponseWriter.Write(b)
+}
+
+func (t *trackingWriter) Flush() {
+	t.wroteHeader = true
+	_ = http.NewResponseController(t.ResponseWriter).Flush()
+}
+
+func (t *trackingWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
@@ -0,0 +1,63 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandle(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler HandlerFunc
+		status  int
+		code    ErrorCode
+	}{
+		{
+			name: "Typed error",
+			handler: func(w http.ResponseWriter, r *http.Request) error {
+				return NewError(http.StatusNotFound, CodePollNotFound, "Poll not found!", nil)
+			},
+			status: http.StatusNotFound,
+			code:   CodePollNotFound,
+		},
+		{
+			name: "Untyped error",
+			handler: func(w http.ResponseWriter, r *http.Request) error {
+				return errors.New("disk full")
+			},
+			status: http.StatusInternalServerError,
+			code:   CodeInternal,
+		},
+		{
+			name: "Panic",
+			handler: func(w http.ResponseWriter, r *http.Request) error {
+				panic("oops")
+			},
+			status: http.StatusInternalServerError,
+			code:   CodeInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			Handle(tt.handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if w.Code != tt.status {
+				t.Fatalf("Expected %d but got %d\n", tt.status, w.Code)
+			}
+
+			var errResponse ErrorResponse
+			err := json.NewDecoder(w.Body).Decode(&errResponse)
+			if err != nil {
+				t.Fatalf("Failed to decode error: %s\n", err)
+			}
+
+			if errResponse.Code != tt.code {
+				t.Fatalf("Expected %s code but got %s\n", tt.code, errResponse.Code)
+			}
+		})
+	}
+}
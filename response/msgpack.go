@@ -0,0 +1,248 @@
+package response
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// MarshalMsgPack encodes v as MessagePack following the same rules as encoding/json:
+// structs become maps keyed by field name (honoring json tag names and "-"),
+// encoding.TextMarshaler values become strings and []byte becomes binary.
+func MarshalMsgPack(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	err := encodeMsgPack(&buf, reflect.ValueOf(v))
+	return buf.Bytes(), err
+}
+
+var textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+
+func encodeMsgPack(buf *bytes.Buffer, v reflect.Value) error {
+	if !v.IsValid() {
+		buf.WriteByte(0xc0)
+		return nil
+	}
+
+	if v.Type().Implements(textMarshalerType) {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			buf.WriteByte(0xc0)
+			return nil
+		}
+
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		writeMsgPackString(buf, string(text))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			buf.WriteByte(0xc0)
+			return nil
+		}
+		return encodeMsgPack(buf, v.Elem())
+
+	case reflect.Bool:
+		if v.Bool() {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeMsgPackInt(buf, v.Int())
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeMsgPackUint(buf, v.Uint())
+
+	case reflect.Float32:
+		buf.WriteByte(0xca)
+		buf.Write(binary.BigEndian.AppendUint32(nil, math.Float32bits(float32(v.Float()))))
+
+	case reflect.Float64:
+		buf.WriteByte(0xcb)
+		buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(v.Float())))
+
+	case reflect.String:
+		writeMsgPackString(buf, v.String())
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			buf.WriteByte(0xc0)
+			return nil
+		}
+
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			writeMsgPackBinary(buf, b)
+			return nil
+		}
+
+		writeMsgPackHeader(buf, v.Len(), 0x90, 0xdc, 0xdd)
+		for i := range v.Len() {
+			if err := encodeMsgPack(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		if v.IsNil() {
+			buf.WriteByte(0xc0)
+			return nil
+		}
+
+		// Keys are sorted by their encoding so the output is deterministic.
+		entries := make([][2][]byte, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			var key, value bytes.Buffer
+			if err := encodeMsgPack(&key, iter.Key()); err != nil {
+				return err
+			}
+			if err := encodeMsgPack(&value, iter.Value()); err != nil {
+				return err
+			}
+			entries = append(entries, [2][]byte{key.Bytes(), value.Bytes()})
+		}
+		slices.SortFunc(entries, func(a, b [2][]byte) int {
+			return bytes.Compare(a[0], b[0])
+		})
+
+		writeMsgPackHeader(buf, len(entries), 0x80, 0xde, 0xdf)
+		for _, entry := range entries {
+			buf.Write(entry[0])
+			buf.Write(entry[1])
+		}
+
+	case reflect.Struct:
+		type field struct {
+			name  string
+			value reflect.Value
+		}
+
+		fields := make([]field, 0, v.NumField())
+		for i := range v.NumField() {
+			structField := v.Type().Field(i)
+			if !structField.IsExported() {
+				continue
+			}
+
+			name := structField.Name
+			if tag, found := structField.Tag.Lookup("json"); found {
+				tagName, _, _ := strings.Cut(tag, ",")
+				if tagName == "-" {
+					continue
+				}
+				if tagName != "" {
+					name = tagName
+				}
+			}
+			fields = append(fields, field{name: name, value: v.Field(i)})
+		}
+
+		writeMsgPackHeader(buf, len(fields), 0x80, 0xde, 0xdf)
+		for _, f := range fields {
+			writeMsgPackString(buf, f.name)
+			if err := encodeMsgPack(buf, f.value); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return fmt.Errorf("can't encode %s as MessagePack", v.Type())
+	}
+
+	return nil
+}
+
+func writeMsgPackInt(buf *bytes.Buffer, n int64) {
+	switch {
+	case n >= 0:
+		writeMsgPackUint(buf, uint64(n))
+	case n >= -32:
+		buf.WriteByte(byte(n))
+	case n >= math.MinInt8:
+		buf.Write([]byte{0xd0, byte(n)})
+	case n >= math.MinInt16:
+		buf.WriteByte(0xd1)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	case n >= math.MinInt32:
+		buf.WriteByte(0xd2)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	default:
+		buf.WriteByte(0xd3)
+		buf.Write(binary.BigEndian.AppendUint64(nil, uint64(n)))
+	}
+}
+
+func writeMsgPackUint(buf *bytes.Buffer, n uint64) {
+	switch {
+	case n <= 0x7f:
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint8:
+		buf.Write([]byte{0xcc, byte(n)})
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xcd)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	case n <= math.MaxUint32:
+		buf.WriteByte(0xce)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	default:
+		buf.WriteByte(0xcf)
+		buf.Write(binary.BigEndian.AppendUint64(nil, n))
+	}
+}
+
+func writeMsgPackString(buf *bytes.Buffer, s string) {
+	switch n := len(s); {
+	case n < 32:
+		buf.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		buf.Write([]byte{0xd9, byte(n)})
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xda)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		buf.WriteByte(0xdb)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+	buf.WriteString(s)
+}
+
+func writeMsgPackBinary(buf *bytes.Buffer, b []byte) {
+	switch n := len(b); {
+	case n <= math.MaxUint8:
+		buf.Write([]byte{0xc4, byte(n)})
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xc5)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		buf.WriteByte(0xc6)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+	buf.Write(b)
+}
+
+// writeMsgPackHeader writes the header of an array or a map,
+// using the fix format if it has less than 16 elements.
+func writeMsgPackHeader(buf *bytes.Buffer, n int, fix byte, code16 byte, code32 byte) {
+	switch {
+	case n < 16:
+		buf.WriteByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(code16)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		buf.WriteByte(code32)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+}
@@ -0,0 +1,187 @@
+package response
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	MediaJSON      = "application/json"
+	MediaJSONLines = "application/jsonl"
+	MediaCSV       = "text/csv"
+	MediaMsgPack   = "application/msgpack"
+	MediaText      = "text/plain"
+)
+
+// Types that can be sent, in order of preference when the client accepts many.
+var supportedMedia = []string{MediaJSON, MediaMsgPack, MediaCSV, MediaJSONLines, MediaText}
+
+// Aliases some clients use for the supported types.
+var mediaAliases = map[string]string{
+	"application/x-msgpack":   MediaMsgPack,
+	"application/vnd.msgpack": MediaMsgPack,
+	"application/x-ndjson":    MediaJSONLines,
+	"application/jsonlines":   MediaJSONLines,
+}
+
+var ErrNotAcceptable = errors.New("the body can't be sent in any of the accepted types")
+
+// Tabular bodies can be sent as CSV, the first row is the header.
+type Tabular interface {
+	Rows() [][]string
+}
+
+// Table is the simplest Tabular body.
+type Table [][]string
+
+func (t Table) Rows() [][]string {
+	return t
+}
+
+// canEncode checks if the body can be sent as the media type.
+func canEncode(body any, mediaType string) bool {
+	switch mediaType {
+	case MediaCSV:
+		_, ok := body.(Tabular)
+		return ok
+	case MediaJSONLines:
+		kind := reflect.ValueOf(body).Kind()
+		return kind == reflect.Slice || kind == reflect.Array
+	case MediaText:
+		switch body.(type) {
+		case string, fmt.Stringer, nil:
+			return true
+		}
+		return false
+	default:
+		return slices.Contains(supportedMedia, mediaType)
+	}
+}
+
+func encode(body any, mediaType string) ([]byte, error) {
+	switch mediaType {
+	case MediaJSON:
+		return json.Marshal(body)
+
+	case MediaMsgPack:
+		return MarshalMsgPack(body)
+
+	case MediaCSV:
+		var buf bytes.Buffer
+		err := csv.NewWriter(&buf).WriteAll(body.(Tabular).Rows())
+		return buf.Bytes(), err
+
+	case MediaJSONLines:
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		items := reflect.ValueOf(body)
+		for i := range items.Len() {
+			if err := encoder.Encode(items.Index(i).Interface()); err != nil {
+				return nil, err
+			}
+		}
+		return buf.Bytes(), nil
+
+	case MediaText:
+		switch b := body.(type) {
+		case string:
+			return []byte(b), nil
+		case fmt.Stringer:
+			return []byte(b.String()), nil
+		}
+		return nil, nil
+	}
+
+	return nil, ErrNotAcceptable
+}
+
+type acceptedMedia struct {
+	mediaType string
+	quality   float64
+}
+
+func parseAccept(accept string) []acceptedMedia {
+	if strings.TrimSpace(accept) == "" {
+		return []acceptedMedia{{mediaType: "*/*", quality: 1}}
+	}
+
+	accepted := make([]acceptedMedia, 0)
+	for part := range strings.SplitSeq(accept, ",") {
+		params := strings.Split(part, ";")
+		media := acceptedMedia{
+			mediaType: strings.ToLower(strings.TrimSpace(params[0])),
+			quality:   1,
+		}
+		if alias, found := mediaAliases[media.mediaType]; found {
+			media.mediaType = alias
+		}
+
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if key != "q" {
+				continue
+			}
+
+			quality, err := strconv.ParseFloat(value, 64)
+			if err == nil {
+				media.quality = quality
+			}
+		}
+
+		if media.mediaType != "" && media.quality > 0 {
+			accepted = append(accepted, media)
+		}
+	}
+
+	slices.SortStableFunc(accepted, func(a, b acceptedMedia) int {
+		switch {
+		case a.quality > b.quality:
+			return -1
+		case a.quality < b.quality:
+			return 1
+		}
+		return 0
+	})
+	return accepted
+}
+
+// Negotiate picks the media type to send the body as, following the Accept header.
+// It returns false if the body can't be sent as any of the accepted types.
+func Negotiate(accept string, body any) (string, bool) {
+	for _, media := range parseAccept(accept) {
+		for _, supported := range supportedMedia {
+			if !mediaMatches(media.mediaType, supported) {
+				continue
+			}
+
+			if canEncode(body, supported) {
+				return supported, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+func mediaMatches(pattern string, mediaType string) bool {
+	if pattern == "*/*" || pattern == mediaType {
+		return true
+	}
+
+	kind, _, _ := strings.Cut(mediaType, "/")
+	return pattern == kind+"/*"
+}
+
+func contentType(mediaType string) string {
+	if strings.HasPrefix(mediaType, "text/") {
+		return mediaType + "; charset=utf-8"
+	}
+	return mediaType
+}
@@ -0,0 +1,88 @@
+package response
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name      string
+		accept    string
+		body      any
+		mediaType string
+		found     bool
+	}{
+		{name: "No Accept header", accept: "", body: ErrorResponse{}, mediaType: MediaJSON, found: true},
+		{name: "Any type", accept: "*/*", body: ErrorResponse{}, mediaType: MediaJSON, found: true},
+		{name: "Quality order", accept: "application/json;q=0.5, application/x-msgpack", body: ErrorResponse{}, mediaType: MediaMsgPack, found: true},
+		{name: "CSV for tables", accept: "text/csv", body: Table{{"Header"}}, mediaType: MediaCSV, found: true},
+		{name: "CSV for non tables", accept: "text/csv", body: ErrorResponse{}, found: false},
+		{name: "Text wildcard", accept: "text/*", body: ErrorResponse{}, mediaType: MediaText, found: true},
+		{name: "JSON Lines for slices", accept: "application/x-ndjson", body: []int{1, 2}, mediaType: MediaJSONLines, found: true},
+		{name: "Unsupported type", accept: "application/xml", body: ErrorResponse{}, found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mediaType, found := Negotiate(tt.accept, tt.body)
+			if found != tt.found || mediaType != tt.mediaType {
+				t.Fatalf("Expected (%q, %t) but got (%q, %t)\n", tt.mediaType, tt.found, mediaType, found)
+			}
+		})
+	}
+}
+
+func TestSend(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/xml")
+
+	w := httptest.NewRecorder()
+	_ = NewResponseBuilder(http.StatusOK).SetBody(Table{{"Header"}}).Send(w, req)
+	if w.Code != http.StatusNotAcceptable {
+		t.Fatalf("Expected 406 but got %d\n", w.Code)
+	}
+
+	post := httptest.NewRequest(http.MethodPost, "/", nil)
+	post.Header.Set("Accept", "text/csv")
+
+	w = httptest.NewRecorder()
+	_ = NewResponseBuilder(http.StatusOK).SetBody(ErrorResponse{Msg: "Voted!"}).Send(w, post)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != MediaJSON {
+		t.Fatalf("Unsafe requests should fall back to JSON but got %d %s\n", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	w = httptest.NewRecorder()
+	_ = NewResponseBuilder(http.StatusNotFound).SetBody(ErrorResponse{Msg: "Poll not found!"}).Send(w, req)
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") != MediaJSON {
+		t.Fatalf("Errors should fall back to JSON but got %d %s\n", w.Code, w.Header().Get("Content-Type"))
+	}
+}
+
+func TestMarshalMsgPack(t *testing.T) {
+	body := struct {
+		Msg   string
+		Count uint
+		Ok    bool
+		Tally map[string]int
+	}{Msg: "hi", Count: 300, Ok: true, Tally: map[string]int{"b": -1, "a": 1}}
+
+	expected := []byte{
+		0x84,
+		0xa3, 'M', 's', 'g', 0xa2, 'h', 'i',
+		0xa5, 'C', 'o', 'u', 'n', 't', 0xcd, 0x01, 0x2c,
+		0xa2, 'O', 'k', 0xc3,
+		0xa5, 'T', 'a', 'l', 'l', 'y', 0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0xff,
+	}
+
+	encoded, err := MarshalMsgPack(body)
+	if err != nil {
+		t.Fatalf("Failed to encode: %s\n", err)
+	}
+
+	if !bytes.Equal(encoded, expected) {
+		t.Fatalf("Encoding doesn't match!\nExpected: %x\nGot: %x", expected, encoded)
+	}
+}
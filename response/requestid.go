@@ -0,0 +1,30 @@
+package response
+
+import (
+	"context"
+	"log/slog"
+)
+
+// RequestIdHeader is read to reuse the id a proxy already assigned and echoed back on responses.
+const RequestIdHeader = "X-Request-ID"
+
+type requestIdKey struct{}
+
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, id)
+}
+
+// RequestId returns the id of the request the context belongs to, empty if it has none.
+func RequestId(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey{}).(string)
+	return id
+}
+
+// Logger logs with the request_id attribute of the context.
+func Logger(ctx context.Context) *slog.Logger {
+	id := RequestId(ctx)
+	if id == "" {
+		return slog.Default()
+	}
+	return slog.Default().With("request_id", id)
+}